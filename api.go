@@ -0,0 +1,269 @@
+// Copyright 2025 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/prometheus-community/ipmi_exporter/freeipmi"
+)
+
+// sensorStateNames are the names of the sensor severities, as used in the
+// sensor state metrics.
+var sensorStateNames = []string{"nominal", "warning", "critical", "non-recoverable"}
+
+// apiSensor is a sensor reading as returned by the JSON API.
+type apiSensor struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+	// Value is null if the sensor has no numeric reading.
+	Value *float64 `json:"value"`
+	Unit  string   `json:"unit"`
+	// State is one of sensorStateNames, or "unknown".
+	State string `json:"state"`
+	// RawState is the state as reported by the backend.
+	RawState string `json:"raw_state"`
+	Event    string `json:"event,omitempty"`
+}
+
+// apiSELEntry is a SEL entry as returned by the JSON API.
+type apiSELEntry struct {
+	ID int64 `json:"id"`
+	// Timestamp is null if the entry has no valid timestamp, e.g. for
+	// events logged before the BMC clock was set.
+	Timestamp *time.Time `json:"timestamp"`
+	Name      string     `json:"name"`
+	Type      string     `json:"type"`
+	State     string     `json:"state"`
+	Event     string     `json:"event"`
+}
+
+// apiResponse is the envelope of all JSON API responses.
+type apiResponse struct {
+	Status string `json:"status"`
+	Data   any    `json:"data,omitempty"`
+	// ErrorType is one of the reasons of the ipmi_collector_error metric.
+	ErrorType string `json:"errorType,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// apiEndpoint serves structured data read by the backend of a collector,
+// FreeIPMI or native, whichever the module uses for the collector.
+type apiEndpoint struct {
+	collector CollectorName
+	freeipmi  func(result freeipmi.Result, target ipmiTarget) (any, error)
+	native    func(ctx context.Context, target ipmiTarget) (any, error)
+}
+
+var (
+	apiSensorsEndpoint = apiEndpoint{
+		collector: IPMICollectorName,
+		freeipmi: func(result freeipmi.Result, target ipmiTarget) (any, error) {
+			data, err := freeipmi.GetSensorData(result, target.config.ExcludeSensorIDs)
+			if err != nil {
+				return nil, err
+			}
+			sensors := make([]apiSensor, 0, len(data))
+			for _, s := range data {
+				sensors = append(sensors, apiSensor{
+					ID:       s.ID,
+					Name:     s.Name,
+					Type:     s.Type,
+					Value:    apiValue(s.Value),
+					Unit:     s.Unit,
+					State:    sensorStateName(sensorStateFreeipmi(s.State)),
+					RawState: s.State,
+					Event:    s.Event,
+				})
+			}
+			return sensors, nil
+		},
+		native: func(ctx context.Context, target ipmiTarget) (any, error) {
+			data, err := readSensorsNative(ctx, target)
+			if err != nil {
+				return nil, err
+			}
+			sensors := make([]apiSensor, 0, len(data))
+			for _, s := range data {
+				sensors = append(sensors, apiSensor{
+					ID:       int64(s.Number),
+					Name:     s.Name,
+					Type:     s.SensorType.String(),
+					Value:    apiValue(s.Value),
+					Unit:     s.SensorUnit.String(),
+					State:    sensorStateName(sensorStateNative(s.Status())),
+					RawState: s.Status(),
+				})
+			}
+			return sensors, nil
+		},
+	}
+
+	apiSELEndpoint = apiEndpoint{
+		collector: SELEventsCollectorName,
+		freeipmi: func(result freeipmi.Result, _ ipmiTarget) (any, error) {
+			data, err := freeipmi.GetSELEvents(result)
+			if err != nil {
+				return nil, err
+			}
+			entries := make([]apiSELEntry, 0, len(data))
+			for _, e := range data {
+				entry := apiSELEntry{
+					ID:    e.ID,
+					Name:  e.Name,
+					Type:  e.Type,
+					State: e.State,
+					Event: e.Event,
+				}
+				// Parsed as UTC, like for the sel_events metrics.
+				if t, err := time.Parse(SELDateTimeFormat, e.Date+" "+e.Time); err == nil {
+					entry.Timestamp = &t
+				}
+				entries = append(entries, entry)
+			}
+			return entries, nil
+		},
+		native: func(ctx context.Context, target ipmiTarget) (any, error) {
+			data, err := readSELEntriesNative(ctx, target, target.config.CollectorOptions.SELEvents.MaxEntries)
+			if err != nil {
+				return nil, err
+			}
+			entries := make([]apiSELEntry, 0, len(data))
+			for _, e := range data {
+				entry := apiSELEntry{
+					ID:   int64(e.RecordID),
+					Type: e.RecordType.String(),
+				}
+				switch {
+				case e.Standard != nil:
+					t := e.Standard.Timestamp
+					entry.Timestamp = &t
+					entry.Name = fmt.Sprintf("Sensor #%d", e.Standard.SensorNumber)
+					entry.Type = e.Standard.SensorType.String()
+					entry.State = string(e.Standard.EventSeverity())
+					entry.Event = e.Standard.EventString()
+				case e.OEMTimestamped != nil:
+					t := e.OEMTimestamped.Timestamp
+					entry.Timestamp = &t
+				}
+				entries = append(entries, entry)
+			}
+			return entries, nil
+		},
+	}
+
+	apiBMCEndpoint = apiEndpoint{
+		collector: BMCCollectorName,
+		freeipmi: func(result freeipmi.Result, target ipmiTarget) (any, error) {
+			return readBMCInfo(result, target)
+		},
+		native: func(ctx context.Context, target ipmiTarget) (any, error) {
+			return readBMCInfoNative(ctx, target)
+		},
+	}
+)
+
+// sensorStateName returns the name of a sensor severity.
+func sensorStateName(state float64) string {
+	if math.IsNaN(state) || int(state) >= len(sensorStateNames) {
+		return "unknown"
+	}
+	return sensorStateNames[int(state)]
+}
+
+// apiValue returns a sensor value, or nil if it is not a number.
+func apiValue(v float64) *float64 {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil
+	}
+	return &v
+}
+
+// ServeHTTP reads the data of a target and writes it as JSON.
+func (e apiEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	target := r.URL.Query().Get("target")
+	if target == "" {
+		writeAPIError(w, http.StatusBadRequest, "", fmt.Errorf("'target' parameter must be specified"))
+		return
+	}
+	module := r.URL.Query().Get("module")
+	if module == "" {
+		module = sc.ModuleForTarget(target)
+	}
+	if !sc.HasModule(module) {
+		writeAPIError(w, http.StatusBadRequest, "", fmt.Errorf("unknown module %q", module))
+		return
+	}
+
+	ctx, cancel, err := scrapeContext(r)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "", err)
+		return
+	}
+	defer cancel()
+	release, err := limiter.acquire(ctx, target)
+	if err != nil {
+		writeAPIError(w, http.StatusServiceUnavailable, "", fmt.Errorf("request rejected: %w", err))
+		return
+	}
+	defer release()
+	if !breaker.allow(target) {
+		writeAPIError(w, http.StatusServiceUnavailable, errorReasonCircuitOpen, errCircuitOpen)
+		return
+	}
+
+	config := sc.ConfigForTarget(target, module)
+	t := ipmiTarget{
+		host:   target,
+		config: config,
+		native: newNativeSession(target, module, config),
+	}
+	defer t.native.close(ctx)
+
+	var data any
+	collector := config.GetCollector(e.collector)
+	if collector.Cmd() == "" {
+		data, err = e.native(ctx, t)
+		if err != nil {
+			t.native.reportError(err)
+		}
+	} else {
+		data, err = e.freeipmi(executeCollector(ctx, collector, t), t)
+	}
+	breaker.record(target, err == nil || !isConnectionFailure(err))
+	if err != nil {
+		logger.Error("API request failed", "target", target, "module", module, "collector", e.collector, "error", err)
+		writeAPIError(w, http.StatusInternalServerError, classifyError(err), err)
+		return
+	}
+	writeAPIResponse(w, http.StatusOK, apiResponse{Status: "success", Data: data})
+}
+
+func writeAPIError(w http.ResponseWriter, code int, errorType string, err error) {
+	writeAPIResponse(w, code, apiResponse{Status: "error", ErrorType: errorType, Error: err.Error()})
+}
+
+func writeAPIResponse(w http.ResponseWriter, code int, resp apiResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		logger.Error("Error writing API response", "error", err)
+	}
+}
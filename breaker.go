@@ -0,0 +1,149 @@
+// Copyright 2025 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	errCircuitOpen = errors.New("circuit breaker open, target is considered unreachable")
+
+	circuitOpenDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "target", "circuit_open"),
+		"'1' if scrapes of the target are currently skipped because it was unreachable, '0' otherwise.",
+		nil,
+		nil,
+	)
+)
+
+// circuitBreaker keeps track of targets that could not be reached. Once a
+// target failed a number of scrapes in a row, further scrapes fail fast for a
+// backoff period. After that, a single probe scrape is let through; if it
+// fails as well, the backoff period is doubled (up to a limit).
+type circuitBreaker struct {
+	threshold  int
+	backoff    time.Duration
+	maxBackoff time.Duration
+
+	mu      sync.Mutex
+	targets map[string]*breakerState
+}
+
+type breakerState struct {
+	failures  int
+	backoff   time.Duration
+	openUntil time.Time
+	probing   bool
+}
+
+func newCircuitBreaker(threshold int, backoff, maxBackoff time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		threshold:  threshold,
+		backoff:    backoff,
+		maxBackoff: maxBackoff,
+		targets:    map[string]*breakerState{},
+	}
+}
+
+// allow returns true if a scrape of the target may proceed. Each allowed
+// scrape must be followed by a call to record. A nil breaker allows all
+// scrapes.
+func (b *circuitBreaker) allow(target string) bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	st, ok := b.targets[target]
+	if !ok || st.failures < b.threshold {
+		return true
+	}
+	if time.Now().Before(st.openUntil) || st.probing {
+		return false
+	}
+	// Half-open: let a single probe through.
+	st.probing = true
+	return true
+}
+
+// record updates the state of the target after a scrape. reachable is false
+// if the target could not be connected to at all.
+func (b *circuitBreaker) record(target string, reachable bool) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if reachable {
+		if st, ok := b.targets[target]; ok && st.failures >= b.threshold {
+			logger.Info("Target reachable again, closing circuit breaker", "target", target)
+		}
+		delete(b.targets, target)
+		return
+	}
+
+	st, ok := b.targets[target]
+	if !ok {
+		st = &breakerState{}
+		b.targets[target] = st
+	}
+	st.failures++
+	st.probing = false
+	if st.failures < b.threshold {
+		return
+	}
+	if st.backoff == 0 {
+		st.backoff = b.backoff
+		logger.Warn("Target unreachable, opening circuit breaker", "target", target, "failures", st.failures, "backoff", st.backoff)
+	} else {
+		st.backoff = min(2*st.backoff, b.maxBackoff)
+		logger.Debug("Target still unreachable", "target", target, "backoff", st.backoff)
+	}
+	st.openUntil = time.Now().Add(st.backoff)
+}
+
+// isOpen returns true if scrapes of the target currently fail fast.
+func (b *circuitBreaker) isOpen(target string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	st, ok := b.targets[target]
+	return ok && st.failures >= b.threshold
+}
+
+// collect sends the breaker state of the target, if the breaker is enabled.
+func (b *circuitBreaker) collect(ch chan<- prometheus.Metric, target string) {
+	if b == nil {
+		return
+	}
+	open := 0.0
+	if b.isOpen(target) {
+		open = 1
+	}
+	ch <- prometheus.MustNewConstMetric(circuitOpenDesc, prometheus.GaugeValue, open)
+}
+
+// isConnectionFailure returns true if err indicates that the target could
+// not be reached at all, as opposed to the target responding with an error.
+func isConnectionFailure(err error) bool {
+	switch classifyError(err) {
+	case errorReasonTimeout, errorReasonConnectionRefused:
+		return true
+	}
+	return false
+}
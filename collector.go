@@ -20,6 +20,7 @@ import (
 	"path"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/bougou/go-ipmi"
@@ -31,24 +32,36 @@ import (
 const (
 	namespace   = "ipmi"
 	targetLocal = ""
+
+	nativeCloseTimeout = 2 * time.Second
 )
 
 type collector interface {
 	Name() CollectorName
 	Cmd() string
 	Args() []string
-	Collect(output freeipmi.Result, ch chan<- prometheus.Metric, target ipmiTarget) (int, error)
+	Collect(ctx context.Context, output freeipmi.Result, ch chan<- prometheus.Metric, target ipmiTarget) (int, error)
 }
 
 type metaCollector struct {
+	// ctx bounds the runtime of all collectors of a scrape. Collectors that
+	// are still running when it is done get cancelled.
+	ctx    context.Context
 	target string
 	module string
 	config *SafeConfig
+	// filter selects the collectors of the module to run.
+	filter collectorFilter
 }
 
 type ipmiTarget struct {
 	host   string
 	config IPMIConfig
+	// native is the native IPMI session shared by all collectors of a scrape.
+	native *nativeSession
+	// trace records the raw output of a collector. It is nil unless the
+	// collector runs for the debug endpoint.
+	trace *collectorTrace
 }
 
 var (
@@ -65,6 +78,20 @@ var (
 		nil,
 		nil,
 	)
+
+	collectorDurationDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "collector", "duration_seconds"),
+		"Returns how long a collector took to complete in seconds.",
+		[]string{"collector"},
+		nil,
+	)
+
+	collectorErrorDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "collector", "error"),
+		"'1' if a collector failed, labeled with the reason of the failure, '0' otherwise.",
+		[]string{"collector", "reason"},
+		nil,
+	)
 )
 
 // Describe implements Prometheus.Collector.
@@ -81,6 +108,22 @@ func markCollectorUp(ch chan<- prometheus.Metric, name string, up int) {
 	)
 }
 
+// markCollectorError reports whether a collector failed and why. A collector
+// that succeeded is reported as 0 with an empty reason.
+func markCollectorError(ch chan<- prometheus.Metric, name string, err error) {
+	value, reason := 0.0, ""
+	if err != nil {
+		value, reason = 1, classifyError(err)
+	}
+	ch <- prometheus.MustNewConstMetric(
+		collectorErrorDesc,
+		prometheus.GaugeValue,
+		value,
+		name,
+		reason,
+	)
+}
+
 // Collect implements Prometheus.Collector.
 func (c metaCollector) Collect(ch chan<- prometheus.Metric) {
 	start := time.Now()
@@ -98,32 +141,175 @@ func (c metaCollector) Collect(ch chan<- prometheus.Metric) {
 	target := ipmiTarget{
 		host:   c.target,
 		config: config,
+		native: newNativeSession(c.target, c.module, config),
 	}
+	defer target.native.close(c.ctx)
 
-	for _, collector := range config.GetCollectors() {
-		var up int
-		logger.Debug("Running collector", "target", target.host, "collector", collector.Name())
+	collectors := c.filter.apply(config.GetCollectors())
+	results := make([]collectorStatus, len(collectors))
+	defer func() {
+		scrapeHistory.record(scrapeStatus{
+			Time:       start,
+			Target:     c.target,
+			Module:     c.module,
+			Duration:   time.Since(start),
+			Collectors: results,
+			Partial:    !c.filter.empty(),
+		})
+	}()
 
-		fqcmd := collector.Cmd()
-		result := freeipmi.Result{}
+	cb := breaker
+	if c.target == targetLocal {
+		cb = nil
+	}
+	defer cb.collect(ch, c.target)
+	if len(collectors) == 0 {
+		// Nothing to tell the breaker, the target was not contacted.
+		return
+	}
+	if !cb.allow(c.target) {
+		logger.Debug("Skipping scrape, target unreachable", "target", c.target)
+		for i, collector := range collectors {
+			name := string(collector.Name())
+			markCollectorUp(ch, name, 0)
+			markCollectorError(ch, name, errCircuitOpen)
+			results[i] = collectorStatus{Name: name, Err: errCircuitOpen}
+		}
+		return
+	}
 
-		// Go-native collectors return empty string as command
-		if fqcmd != "" {
-			if !path.IsAbs(fqcmd) {
-				fqcmd = path.Join(*executablesPath, collector.Cmd())
+	limit := config.CollectorConcurrency
+	if limit <= 0 || limit > len(collectors) {
+		limit = len(collectors)
+	}
+	sem := make(chan struct{}, limit)
+	var wg sync.WaitGroup
+	for i, collector := range collectors {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-c.ctx.Done():
 			}
-			args := collector.Args()
-			cfg := config.GetFreeipmiConfig()
+			start := time.Now()
+			err := c.runCollector(collector, ch, target)
+			results[i] = collectorStatus{
+				Name:     string(collector.Name()),
+				Duration: time.Since(start),
+				Err:      err,
+			}
+		}()
+	}
+	wg.Wait()
 
-			result = freeipmi.Execute(fqcmd, args, cfg, target.host, logger)
+	// The target counts as unreachable only if no collector got through to it.
+	reachable := false
+	for _, r := range results {
+		if err := r.Err; err == nil || !isConnectionFailure(err) {
+			reachable = true
+			break
 		}
+	}
+	cb.record(c.target, reachable)
+}
 
-		up, err := collector.Collect(result, ch, target)
-		if err != nil {
-			logger.Error("Collector failed", "name", collector.Name(), "error", err)
+// runCollector runs a single collector and reports its status via ipmi_up,
+// returning the error it failed with, if any. It is safe to call concurrently
+// for different collectors of the same target.
+func (c metaCollector) runCollector(collector collector, ch chan<- prometheus.Metric, target ipmiTarget) error {
+	name := string(collector.Name())
+	if err := c.ctx.Err(); err != nil {
+		logger.Error("Collector not started", "target", targetName(target.host), "name", name, "error", err)
+		markCollectorUp(ch, name, 0)
+		markCollectorError(ch, name, err)
+		return err
+	}
+	logger.Debug("Running collector", "target", target.host, "collector", name)
+
+	start := time.Now()
+	defer func() {
+		ch <- prometheus.MustNewConstMetric(
+			collectorDurationDesc,
+			prometheus.GaugeValue,
+			time.Since(start).Seconds(),
+			name,
+		)
+	}()
+
+	result := executeCollector(c.ctx, collector, target)
+	up, err := collector.Collect(c.ctx, result, ch, target)
+	if err != nil {
+		if collector.Cmd() == "" {
+			target.native.reportError(err)
 		}
-		markCollectorUp(ch, string(collector.Name()), up)
+		if ctxErr := c.ctx.Err(); ctxErr != nil {
+			logger.Error("Collector did not finish in time", "target", targetName(target.host), "name", name, "error", ctxErr)
+			err = ctxErr
+		} else {
+			logger.Error("Collector failed", "name", name, "error", err)
+		}
+		up = 0
+	}
+	markCollectorError(ch, name, err)
+	markCollectorUp(ch, name, up)
+	return err
+}
+
+// executeCollector runs the FreeIPMI tool of a collector and returns its
+// result. Native collectors do not run anything and get an empty result.
+func executeCollector(ctx context.Context, collector collector, target ipmiTarget) freeipmi.Result {
+	fqcmd := collector.Cmd()
+	// Go-native collectors return empty string as command
+	if fqcmd == "" {
+		return freeipmi.Result{}
 	}
+	if !path.IsAbs(fqcmd) {
+		fqcmd = path.Join(*executablesPath, collector.Cmd())
+	}
+	args := collector.Args()
+	cfg := target.config.GetFreeipmiConfig()
+
+	result := freeipmi.Execute(ctx, fqcmd, args, cfg, target.host, logger)
+	target.trace.freeipmi(result)
+	return result
+}
+
+// collectMetrics runs a collector and returns all metrics it produced.
+func collectMetrics(c prometheus.Collector) []prometheus.Metric {
+	ch := make(chan prometheus.Metric)
+	done := make(chan []prometheus.Metric)
+	go func() {
+		var metrics []prometheus.Metric
+		for m := range ch {
+			metrics = append(metrics, m)
+		}
+		done <- metrics
+	}()
+	c.Collect(ch)
+	close(ch)
+	return <-done
+}
+
+// metricsCollector replays previously collected metrics.
+type metricsCollector []prometheus.Metric
+
+// Describe implements Prometheus.Collector.
+func (c metricsCollector) Describe(_ chan<- *prometheus.Desc) {
+	// all metrics are described ad-hoc
+}
+
+// Collect implements Prometheus.Collector.
+func (c metricsCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, m := range c {
+		ch <- m
+	}
+}
+
+// targetKey identifies a target/module combination, e.g. in caches.
+func targetKey(target, module string) string {
+	return module + "\x00" + target
 }
 
 func targetName(target string) string {
@@ -187,6 +373,10 @@ func NewNativeClient(ctx context.Context, target ipmiTarget) (*ipmi.Client, erro
 }
 
 func CloseNativeClient(ctx context.Context, client *ipmi.Client) {
+	// Closing the session should still be attempted if the scrape ran out of
+	// time, otherwise the session lingers on the BMC until it times out.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), nativeCloseTimeout)
+	defer cancel()
 	if closeErr := client.Close(ctx); closeErr != nil {
 		logger.Warn("Failed to close IPMI client", "target", client.Host, "error", closeErr)
 	}
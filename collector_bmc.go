@@ -14,6 +14,8 @@
 package main
 
 import (
+	"context"
+
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/prometheus-community/ipmi_exporter/freeipmi"
@@ -46,34 +48,54 @@ func (c BMCCollector) Args() []string {
 	return []string{}
 }
 
-func (c BMCCollector) Collect(result freeipmi.Result, ch chan<- prometheus.Metric, target ipmiTarget) (int, error) {
-	firmwareRevision, err := freeipmi.GetBMCInfoFirmwareRevision(result)
+// bmcInfo holds details about a BMC. Fields that are not available, or not
+// supported by the backend, are "N/A" or empty.
+type bmcInfo struct {
+	FirmwareRevision      string `json:"firmware_revision"`
+	Manufacturer          string `json:"manufacturer,omitempty"`
+	ManufacturerID        string `json:"manufacturer_id"`
+	SystemFirmwareVersion string `json:"system_firmware_version"`
+	BMCURL                string `json:"bmc_url,omitempty"`
+}
+
+// readBMCInfo extracts the BMC details from the output of bmc-info.
+func readBMCInfo(result freeipmi.Result, target ipmiTarget) (bmcInfo, error) {
+	var info bmcInfo
+	var err error
+	info.FirmwareRevision, err = freeipmi.GetBMCInfoFirmwareRevision(result)
 	if err != nil {
-		logger.Error("Failed to collect BMC data", "target", targetName(target.host), "error", err)
-		return 0, err
+		return info, err
 	}
-	manufacturerID, err := freeipmi.GetBMCInfoManufacturerID(result)
+	info.ManufacturerID, err = freeipmi.GetBMCInfoManufacturerID(result)
 	if err != nil {
-		logger.Error("Failed to collect BMC data", "target", targetName(target.host), "error", err)
-		return 0, err
+		return info, err
 	}
-	systemFirmwareVersion, err := freeipmi.GetBMCInfoSystemFirmwareVersion(result)
+	info.SystemFirmwareVersion, err = freeipmi.GetBMCInfoSystemFirmwareVersion(result)
 	if err != nil {
 		// This one is not always available.
 		logger.Debug("Failed to parse bmc-info data", "target", targetName(target.host), "error", err)
-		systemFirmwareVersion = "N/A"
+		info.SystemFirmwareVersion = "N/A"
 	}
-	bmcURL, err := freeipmi.GetBMCInfoBmcURL(result)
+	info.BMCURL, err = freeipmi.GetBMCInfoBmcURL(result)
 	if err != nil {
 		// This one is not always available.
 		logger.Debug("Failed to parse bmc-info data", "target", targetName(target.host), "error", err)
-		bmcURL = "N/A"
+		info.BMCURL = "N/A"
+	}
+	return info, nil
+}
+
+func (c BMCCollector) Collect(_ context.Context, result freeipmi.Result, ch chan<- prometheus.Metric, target ipmiTarget) (int, error) {
+	info, err := readBMCInfo(result, target)
+	if err != nil {
+		logger.Error("Failed to collect BMC data", "target", targetName(target.host), "error", err)
+		return 0, err
 	}
 	ch <- prometheus.MustNewConstMetric(
 		bmcInfoDesc,
 		prometheus.GaugeValue,
 		1,
-		firmwareRevision, manufacturerID, systemFirmwareVersion, bmcURL,
+		info.FirmwareRevision, info.ManufacturerID, info.SystemFirmwareVersion, info.BMCURL,
 	)
 	return 1, nil
 }
@@ -47,16 +47,17 @@ func (c BMCNativeCollector) Args() []string {
 	return []string{}
 }
 
-func (c BMCNativeCollector) Collect(_ freeipmi.Result, ch chan<- prometheus.Metric, target ipmiTarget) (int, error) {
-	ctx := context.TODO()
-	client, err := NewNativeClient(ctx, target)
+// readBMCInfoNative reads the BMC details via the native IPMI session.
+func readBMCInfoNative(ctx context.Context, target ipmiTarget) (bmcInfo, error) {
+	client, err := target.native.acquire(ctx)
 	if err != nil {
-		return 0, err
+		return bmcInfo{}, err
 	}
-	defer CloseNativeClient(ctx, client)
+	defer target.native.release()
 	res, err := client.GetDeviceID(ctx)
+	target.trace.native("GetDeviceID", res, err)
 	if err != nil {
-		return 0, err
+		return bmcInfo{}, err
 	}
 
 	// The API looks slightly awkward here, but doing this instead of calling
@@ -68,6 +69,7 @@ func (c BMCNativeCollector) Collect(_ freeipmi.Result, ch chan<- prometheus.Metr
 		SystemFirmwareVersions: make([]*ipmi.SystemInfoParam_SystemFirmwareVersion, 0),
 	}
 	err = client.GetSystemInfoParamsFor(ctx, &systemInfo)
+	target.trace.native("GetSystemInfoParams", systemInfo, err)
 	// This one is not always available
 	systemFirmwareVersion := "N/A"
 	if err != nil {
@@ -76,14 +78,27 @@ func (c BMCNativeCollector) Collect(_ freeipmi.Result, ch chan<- prometheus.Metr
 		systemFirmwareVersion = systemInfo.ToSystemInfo().SystemFirmwareVersion
 	}
 
+	return bmcInfo{
+		FirmwareRevision:      res.FirmwareVersionStr(),
+		Manufacturer:          ipmi.OEM(res.ManufacturerID).String(),
+		ManufacturerID:        strconv.FormatUint(uint64(res.ManufacturerID), 10),
+		SystemFirmwareVersion: systemFirmwareVersion,
+	}, nil
+}
+
+func (c BMCNativeCollector) Collect(ctx context.Context, _ freeipmi.Result, ch chan<- prometheus.Metric, target ipmiTarget) (int, error) {
+	info, err := readBMCInfoNative(ctx, target)
+	if err != nil {
+		return 0, err
+	}
 	ch <- prometheus.MustNewConstMetric(
 		bmcNativeInfoDesc,
 		prometheus.GaugeValue,
 		1,
-		res.FirmwareVersionStr(),
-		ipmi.OEM(res.ManufacturerID).String(),
-		strconv.FormatUint(uint64(res.ManufacturerID), 10),
-		systemFirmwareVersion,
+		info.FirmwareRevision,
+		info.Manufacturer,
+		info.ManufacturerID,
+		info.SystemFirmwareVersion,
 	)
 	return 1, nil
 }
@@ -14,6 +14,8 @@
 package main
 
 import (
+	"context"
+
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/prometheus-community/ipmi_exporter/freeipmi"
@@ -91,7 +93,7 @@ func (c BMCWatchdogCollector) Args() []string {
 	return []string{"--get"}
 }
 
-func (c BMCWatchdogCollector) Collect(result freeipmi.Result, ch chan<- prometheus.Metric, target ipmiTarget) (int, error) {
+func (c BMCWatchdogCollector) Collect(_ context.Context, result freeipmi.Result, ch chan<- prometheus.Metric, target ipmiTarget) (int, error) {
 	timerState, err := freeipmi.GetBMCWatchdogTimerState(result)
 	if err != nil {
 		logger.Error("Failed to collect BMC watchdog timer", "target", targetName(target.host), "error", err)
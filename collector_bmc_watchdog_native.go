@@ -91,17 +91,17 @@ func (c BMCWatchdogNativeCollector) Args() []string {
 	return []string{}
 }
 
-func (c BMCWatchdogNativeCollector) Collect(_ freeipmi.Result, ch chan<- prometheus.Metric, target ipmiTarget) (int, error) {
+func (c BMCWatchdogNativeCollector) Collect(ctx context.Context, _ freeipmi.Result, ch chan<- prometheus.Metric, target ipmiTarget) (int, error) {
 
 	// TODO this now works remotely
 
-	ctx := context.TODO()
-	client, err := NewNativeClient(ctx, target)
+	client, err := target.native.acquire(ctx)
 	if err != nil {
 		return 0, err
 	}
-	defer CloseNativeClient(ctx, client)
+	defer target.native.release()
 	res, err := client.GetWatchdogTimer(ctx)
+	target.trace.native("GetWatchdogTimer", res, err)
 	if err != nil {
 		return 0, err
 	}
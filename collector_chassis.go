@@ -14,6 +14,8 @@
 package main
 
 import (
+	"context"
+
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/prometheus-community/ipmi_exporter/freeipmi"
@@ -58,7 +60,7 @@ func (c ChassisCollector) Args() []string {
 	return []string{"--get-chassis-status"}
 }
 
-func (c ChassisCollector) Collect(result freeipmi.Result, ch chan<- prometheus.Metric, target ipmiTarget) (int, error) {
+func (c ChassisCollector) Collect(_ context.Context, result freeipmi.Result, ch chan<- prometheus.Metric, target ipmiTarget) (int, error) {
 	currentChassisPowerState, err := freeipmi.GetChassisPowerState(result)
 	if err != nil {
 		logger.Error("Failed to collect chassis data", "target", targetName(target.host), "error", err)
@@ -57,14 +57,14 @@ func (c ChassisNativeCollector) Args() []string {
 	return []string{}
 }
 
-func (c ChassisNativeCollector) Collect(_ freeipmi.Result, ch chan<- prometheus.Metric, target ipmiTarget) (int, error) {
-	ctx := context.TODO()
-	client, err := NewNativeClient(ctx, target)
+func (c ChassisNativeCollector) Collect(ctx context.Context, _ freeipmi.Result, ch chan<- prometheus.Metric, target ipmiTarget) (int, error) {
+	client, err := target.native.acquire(ctx)
 	if err != nil {
 		return 0, err
 	}
-	defer CloseNativeClient(ctx, client)
+	defer target.native.release()
 	res, err := client.GetChassisStatus(ctx)
+	target.trace.native("GetChassisStatus", res, err)
 	if err != nil {
 		return 0, err
 	}
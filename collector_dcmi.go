@@ -14,6 +14,8 @@
 package main
 
 import (
+	"context"
+
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/prometheus-community/ipmi_exporter/freeipmi"
@@ -46,7 +48,7 @@ func (c DCMICollector) Args() []string {
 	return []string{"--get-system-power-statistics"}
 }
 
-func (c DCMICollector) Collect(result freeipmi.Result, ch chan<- prometheus.Metric, target ipmiTarget) (int, error) {
+func (c DCMICollector) Collect(_ context.Context, result freeipmi.Result, ch chan<- prometheus.Metric, target ipmiTarget) (int, error) {
 	currentPowerConsumption, err := freeipmi.GetCurrentPowerConsumption(result)
 	if err != nil {
 		logger.Error("Failed to collect DCMI data", "target", targetName(target.host), "error", err)
@@ -45,14 +45,14 @@ func (c DCMINativeCollector) Args() []string {
 	return []string{}
 }
 
-func (c DCMINativeCollector) Collect(_ freeipmi.Result, ch chan<- prometheus.Metric, target ipmiTarget) (int, error) {
-	ctx := context.TODO()
-	client, err := NewNativeClient(ctx, target)
+func (c DCMINativeCollector) Collect(ctx context.Context, _ freeipmi.Result, ch chan<- prometheus.Metric, target ipmiTarget) (int, error) {
+	client, err := target.native.acquire(ctx)
 	if err != nil {
 		return 0, err
 	}
-	defer CloseNativeClient(ctx, client)
+	defer target.native.release()
 	res, err := client.GetDCMIPowerReading(ctx)
+	target.trace.native("GetDCMIPowerReading", res, err)
 	if err != nil {
 		logger.Error("Failed to collect DCMI data", "target", targetName(target.host), "error", err)
 		return 0, err
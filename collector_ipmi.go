@@ -14,9 +14,9 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"math"
-	"strconv"
 
 	"github.com/prometheus/client_golang/prometheus"
 
@@ -27,100 +27,13 @@ const (
 	IPMICollectorName CollectorName = "ipmi"
 )
 
-var (
-	sensorStateDesc = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, "sensor", "state"),
-		"Indicates the severity of the state reported by an IPMI sensor (0=nominal, 1=warning, 2=critical).",
-		[]string{"id", "name", "type"},
-		nil,
-	)
+// ipmiSensorFamilies are the sensor metric families for modules without
+// sensor rules adding labels.
+var ipmiSensorFamilies = newSensorFamilies(sensorStatesFreeipmi, nil)
 
-	sensorValueDesc = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, "sensor", "value"),
-		"Generic data read from an IPMI sensor of unknown type, relying on labels for context.",
-		[]string{"id", "name", "type"},
-		nil,
-	)
-
-	fanSpeedRPMDesc = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, "fan_speed", "rpm"),
-		"Fan speed in rotations per minute.",
-		[]string{"id", "name"},
-		nil,
-	)
-
-	fanSpeedRatioDesc = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, "fan_speed", "ratio"),
-		"Fan speed as a proportion of the maximum speed.",
-		[]string{"id", "name"},
-		nil,
-	)
-
-	fanSpeedStateDesc = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, "fan_speed", "state"),
-		"Reported state of a fan speed sensor (0=nominal, 1=warning, 2=critical).",
-		[]string{"id", "name"},
-		nil,
-	)
-
-	temperatureDesc = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, "temperature", "celsius"),
-		"Temperature reading in degree Celsius.",
-		[]string{"id", "name"},
-		nil,
-	)
-
-	temperatureStateDesc = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, "temperature", "state"),
-		"Reported state of a temperature sensor (0=nominal, 1=warning, 2=critical).",
-		[]string{"id", "name"},
-		nil,
-	)
-
-	voltageDesc = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, "voltage", "volts"),
-		"Voltage reading in Volts.",
-		[]string{"id", "name"},
-		nil,
-	)
-
-	voltageStateDesc = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, "voltage", "state"),
-		"Reported state of a voltage sensor (0=nominal, 1=warning, 2=critical).",
-		[]string{"id", "name"},
-		nil,
-	)
-
-	currentDesc = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, "current", "amperes"),
-		"Current reading in Amperes.",
-		[]string{"id", "name"},
-		nil,
-	)
-
-	currentStateDesc = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, "current", "state"),
-		"Reported state of a current sensor (0=nominal, 1=warning, 2=critical).",
-		[]string{"id", "name"},
-		nil,
-	)
-
-	powerDesc = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, "power", "watts"),
-		"Power reading in Watts.",
-		[]string{"id", "name"},
-		nil,
-	)
-
-	powerStateDesc = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, "power", "state"),
-		"Reported state of a power sensor (0=nominal, 1=warning, 2=critical).",
-		[]string{"id", "name"},
-		nil,
-	)
-)
-
-type IPMICollector struct{}
+type IPMICollector struct {
+	options IPMICollectorOptions
+}
 
 func (c IPMICollector) Name() CollectorName {
 	return IPMICollectorName
@@ -131,7 +44,7 @@ func (c IPMICollector) Cmd() string {
 }
 
 func (c IPMICollector) Args() []string {
-	return []string{
+	args := []string{
 		"--quiet-cache",
 		"--ignore-unrecognized-events",
 		"--comma-separated-output",
@@ -140,9 +53,36 @@ func (c IPMICollector) Args() []string {
 		"--output-event-bitmask",
 		"--output-sensor-state",
 	}
+	if c.options.BridgeSensors {
+		args = append(args, "--bridge-sensors")
+	}
+	if c.options.InterpretOEMData {
+		args = append(args, "--interpret-oem-data")
+	}
+	if c.options.SharedSensors {
+		args = append(args, "--shared-sensors")
+	}
+	if c.options.SDRCacheDir != "" {
+		args = append(args, "--sdr-cache-directory="+c.options.SDRCacheDir)
+	}
+	return args
+}
+
+// sensorStateFreeipmi maps a sensor state reported by FreeIPMI to its
+// severity, or NaN if the state is unknown.
+func sensorStateFreeipmi(state string) float64 {
+	switch state {
+	case "Nominal":
+		return 0
+	case "Warning":
+		return 1
+	case "Critical":
+		return 2
+	}
+	return math.NaN()
 }
 
-func (c IPMICollector) Collect(result freeipmi.Result, ch chan<- prometheus.Metric, target ipmiTarget) (int, error) {
+func (c IPMICollector) Collect(_ context.Context, result freeipmi.Result, ch chan<- prometheus.Metric, target ipmiTarget) (int, error) {
 	excludeIDs := target.config.ExcludeSensorIDs
 	targetHost := targetName(target.host)
 	results, err := freeipmi.GetSensorData(result, excludeIDs)
@@ -150,98 +90,50 @@ func (c IPMICollector) Collect(result freeipmi.Result, ch chan<- prometheus.Metr
 		logger.Error("Failed to collect sensor data", "target", targetHost, "error", err)
 		return 0, err
 	}
+	rules := target.config.SensorRules
+	labelNames := sensorLabelNames(rules)
+	families := ipmiSensorFamilies
+	if len(labelNames) > 0 {
+		families = newSensorFamilies(sensorStatesFreeipmi, labelNames)
+	}
 	for _, data := range results {
-		var state float64
-
-		switch data.State {
-		case "Nominal":
-			state = 0
-		case "Warning":
-			state = 1
-		case "Critical":
-			state = 2
-		case "N/A":
-			state = math.NaN()
-		default:
+		state := sensorStateFreeipmi(data.State)
+		if math.IsNaN(state) && data.State != "N/A" {
 			logger.Error("Unknown sensor state", "target", targetHost, "state", data.State)
-			state = math.NaN()
 		}
 
 		logger.Debug("Got values", "target", targetHost, "data", fmt.Sprintf("%+v", data))
 
+		metric := sensorMetricGeneric
 		switch data.Unit {
 		case "RPM":
-			collectTypedSensor(ch, fanSpeedRPMDesc, fanSpeedStateDesc, state, data, 1.0)
+			metric = sensorMetricFanSpeedRPM
 		case "C":
-			collectTypedSensor(ch, temperatureDesc, temperatureStateDesc, state, data, 1.0)
+			metric = sensorMetricTemperature
 		case "A":
-			collectTypedSensor(ch, currentDesc, currentStateDesc, state, data, 1.0)
+			metric = sensorMetricCurrent
 		case "V":
-			collectTypedSensor(ch, voltageDesc, voltageStateDesc, state, data, 1.0)
+			metric = sensorMetricVoltage
 		case "W":
-			collectTypedSensor(ch, powerDesc, powerStateDesc, state, data, 1.0)
+			metric = sensorMetricPower
 		case "%":
-			switch data.Type {
-			case "Fan":
-				collectTypedSensor(ch, fanSpeedRatioDesc, fanSpeedStateDesc, state, data, 0.01)
-			default:
-				collectGenericSensor(ch, state, data)
+			if data.Type == "Fan" {
+				metric = sensorMetricFanSpeedRatio
 			}
-		default:
-			collectGenericSensor(ch, state, data)
 		}
+		reading := sensorReading{
+			id:    data.ID,
+			name:  data.Name,
+			typ:   data.Type,
+			unit:  data.Unit,
+			value: data.Value,
+			state: state,
+		}
+		families.collect(ch, rules, labelNames, reading, metric)
 	}
 	return 1, nil
 }
 
 func (c IPMICollector) Describe(ch chan<- *prometheus.Desc) {
-	ch <- sensorStateDesc
-	ch <- sensorValueDesc
-	ch <- fanSpeedRPMDesc
-	ch <- fanSpeedRatioDesc
-	ch <- fanSpeedStateDesc
-	ch <- temperatureDesc
-	ch <- temperatureStateDesc
-	ch <- voltageDesc
-	ch <- voltageStateDesc
-	ch <- currentDesc
-	ch <- currentStateDesc
-	ch <- powerDesc
-	ch <- powerStateDesc
-}
-
-func collectTypedSensor(ch chan<- prometheus.Metric, desc, stateDesc *prometheus.Desc, state float64, data freeipmi.SensorData, scale float64) {
-	ch <- prometheus.MustNewConstMetric(
-		desc,
-		prometheus.GaugeValue,
-		data.Value*scale,
-		strconv.FormatInt(data.ID, 10),
-		data.Name,
-	)
-	ch <- prometheus.MustNewConstMetric(
-		stateDesc,
-		prometheus.GaugeValue,
-		state,
-		strconv.FormatInt(data.ID, 10),
-		data.Name,
-	)
-}
-
-func collectGenericSensor(ch chan<- prometheus.Metric, state float64, data freeipmi.SensorData) {
-	ch <- prometheus.MustNewConstMetric(
-		sensorValueDesc,
-		prometheus.GaugeValue,
-		data.Value,
-		strconv.FormatInt(data.ID, 10),
-		data.Name,
-		data.Type,
-	)
-	ch <- prometheus.MustNewConstMetric(
-		sensorStateDesc,
-		prometheus.GaugeValue,
-		state,
-		strconv.FormatInt(data.ID, 10),
-		data.Name,
-		data.Type,
-	)
+	ipmiSensorFamilies.describe(ch)
 }
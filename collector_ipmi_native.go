@@ -26,100 +26,15 @@ import (
 	"github.com/prometheus-community/ipmi_exporter/freeipmi"
 )
 
-var (
-	sensorStateNativeDesc = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, "sensor", "state"),
-		"Indicates the severity of the state reported by an IPMI sensor (0=nominal, 1=warning, 2=critical, 3=non-recoverable).",
-		[]string{"id", "name", "type"},
-		nil,
-	)
-
-	sensorValueNativeDesc = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, "sensor", "value"),
-		"Generic data read from an IPMI sensor of unknown type, relying on labels for context.",
-		[]string{"id", "name", "type"},
-		nil,
-	)
-
-	fanSpeedRPMNativeDesc = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, "fan_speed", "rpm"),
-		"Fan speed in rotations per minute.",
-		[]string{"id", "name"},
-		nil,
-	)
-
-	fanSpeedRatioNativeDesc = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, "fan_speed", "ratio"),
-		"Fan speed as a proportion of the maximum speed.",
-		[]string{"id", "name"},
-		nil,
-	)
-
-	fanSpeedStateNativeDesc = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, "fan_speed", "state"),
-		"Reported state of a fan speed sensor (0=nominal, 1=warning, 2=critical, 3=non-recoverable).",
-		[]string{"id", "name"},
-		nil,
-	)
-
-	temperatureNativeDesc = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, "temperature", "celsius"),
-		"Temperature reading in degree Celsius.",
-		[]string{"id", "name"},
-		nil,
-	)
-
-	temperatureStateNativeDesc = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, "temperature", "state"),
-		"Reported state of a temperature sensor (0=nominal, 1=warning, 2=critical, 3=non-recoverable).",
-		[]string{"id", "name"},
-		nil,
-	)
-
-	voltageNativeDesc = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, "voltage", "volts"),
-		"Voltage reading in Volts.",
-		[]string{"id", "name"},
-		nil,
-	)
-
-	voltageStateNativeDesc = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, "voltage", "state"),
-		"Reported state of a voltage sensor (0=nominal, 1=warning, 2=critical, 3=non-recoverable).",
-		[]string{"id", "name"},
-		nil,
-	)
-
-	currentNativeDesc = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, "current", "amperes"),
-		"Current reading in Amperes.",
-		[]string{"id", "name"},
-		nil,
-	)
-
-	currentStateNativeDesc = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, "current", "state"),
-		"Reported state of a current sensor (0=nominal, 1=warning, 2=critical, 3=non-recoverable).",
-		[]string{"id", "name"},
-		nil,
-	)
-
-	powerNativeDesc = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, "power", "watts"),
-		"Power reading in Watts.",
-		[]string{"id", "name"},
-		nil,
-	)
-
-	powerStateNativeDesc = prometheus.NewDesc(
-		prometheus.BuildFQName(namespace, "power", "state"),
-		"Reported state of a power sensor (0=nominal, 1=warning, 2=critical, 3=non-recoverable).",
-		[]string{"id", "name"},
-		nil,
-	)
-)
-
-type IPMINativeCollector struct{}
+// ipmiNativeSensorFamilies are the sensor metric families for modules without
+// sensor rules adding labels.
+var ipmiNativeSensorFamilies = newSensorFamilies(sensorStatesNative, nil)
+
+type IPMINativeCollector struct {
+	// None of the options has a native equivalent yet, see
+	// IPMICollectorOptions.unsupportedNative.
+	options IPMICollectorOptions
+}
 
 func (c IPMINativeCollector) Name() CollectorName {
 	// The name is intentionally the same as the non-native collector
@@ -134,45 +49,56 @@ func (c IPMINativeCollector) Args() []string {
 	return []string{}
 }
 
-func (c IPMINativeCollector) Collect(_ freeipmi.Result, ch chan<- prometheus.Metric, target ipmiTarget) (int, error) {
-	excludeIDs := target.config.ExcludeSensorIDs
-	targetHost := targetName(target.host)
+// sensorStateNative maps a sensor status reported by go-ipmi to its severity,
+// or NaN if the status is unknown.
+func sensorStateNative(status string) float64 {
+	switch status {
+	case "ok":
+		return 0
+	case "lnc", "unc": // lower/upper non-critical
+		return 1
+	case "lcr", "ucr": // lower/upper critical
+		return 2
+	case "lnr", "unr": // lower/upper non-recoverable
+		return 3 // TODO this is new
+	}
+	return math.NaN()
+}
 
+// readSensorsNative reads all sensors not excluded in the config via the
+// native IPMI session.
+func readSensorsNative(ctx context.Context, target ipmiTarget) ([]*ipmi.Sensor, error) {
+	excludeIDs := target.config.ExcludeSensorIDs
 	filter := func(sensor *ipmi.Sensor) bool {
 		return !slices.Contains(excludeIDs, int64(sensor.Number))
 	}
 
-	ctx := context.TODO()
-	client, err := NewNativeClient(ctx, target)
+	client, err := target.native.acquire(ctx)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	defer CloseNativeClient(ctx, client)
+	defer target.native.release()
 	res, err := client.GetSensors(ctx, filter)
+	target.trace.native("GetSensors", res, err)
+	return res, err
+}
+
+func (c IPMINativeCollector) Collect(ctx context.Context, _ freeipmi.Result, ch chan<- prometheus.Metric, target ipmiTarget) (int, error) {
+	targetHost := targetName(target.host)
+	res, err := readSensorsNative(ctx, target)
 	if err != nil {
 		return 0, err
 	}
 
-	// results, err := freeipmi.GetSensorData(result, excludeIds)
-	// if err != nil {
-	// 	logger.Error("Failed to collect sensor data", "target", targetHost, "error", err)
-	// 	return 0, err
-	// }
+	rules := target.config.SensorRules
+	labelNames := sensorLabelNames(rules)
+	families := ipmiNativeSensorFamilies
+	if len(labelNames) > 0 {
+		families = newSensorFamilies(sensorStatesNative, labelNames)
+	}
 	for _, data := range res {
-		var state float64
-
-		switch data.Status() {
-		case "ok":
-			state = 0
-		case "lnc", "unc": // lower/upper non-critical
-			state = 1
-		case "lcr", "ucr": // lower/upper critical
-			state = 2
-		case "lnr", "unr": // lower/upper non-recoverable
-			state = 3 // TODO this is new
-		case "N/A":
-			state = math.NaN()
-		default:
+		state := sensorStateNative(data.Status())
+		if math.IsNaN(state) && data.Status() != "N/A" {
 			// TODO handle threshold sensor data
 			logger.Error(
 				"Unknown sensor state",
@@ -180,83 +106,41 @@ func (c IPMINativeCollector) Collect(_ freeipmi.Result, ch chan<- prometheus.Met
 				"state", data.Status(),
 				"sensor_id", strconv.FormatInt(int64(data.Number), 10),
 			)
-			state = math.NaN()
 		}
 
 		logger.Debug("Got values", "target", targetHost, "data", fmt.Sprintf("%+v", data))
 
 		// TODO this could be greatly improved, now that we have structured data available
+		metric := sensorMetricGeneric
 		switch data.SensorUnit.BaseUnit {
 		case ipmi.SensorUnitType_RPM:
 			if data.SensorUnit.Percentage {
-				collectTypedSensorNative(ch, fanSpeedRatioNativeDesc, fanSpeedStateNativeDesc, state, data, 0.01)
+				metric = sensorMetricFanSpeedRatio
 			} else {
-
-				collectTypedSensorNative(ch, fanSpeedRPMNativeDesc, fanSpeedStateNativeDesc, state, data, 1.0)
+				metric = sensorMetricFanSpeedRPM
 			}
 		case ipmi.SensorUnitType_DegreesC:
-			collectTypedSensorNative(ch, temperatureNativeDesc, temperatureStateNativeDesc, state, data, 1.0)
+			metric = sensorMetricTemperature
 		case ipmi.SensorUnitType_Amps:
-			collectTypedSensorNative(ch, currentNativeDesc, currentStateNativeDesc, state, data, 1.0)
+			metric = sensorMetricCurrent
 		case ipmi.SensorUnitType_Volts:
-			collectTypedSensorNative(ch, voltageNativeDesc, voltageStateNativeDesc, state, data, 1.0)
+			metric = sensorMetricVoltage
 		case ipmi.SensorUnitType_Watts:
-			collectTypedSensorNative(ch, powerNativeDesc, powerStateNativeDesc, state, data, 1.0)
-		default:
-			collectGenericSensorNative(ch, state, data)
+			metric = sensorMetricPower
+		}
+		reading := sensorReading{
+			id:    int64(data.Number),
+			name:  data.Name,
+			typ:   data.SensorType.String(),
+			unit:  data.SensorUnit.String(),
+			value: data.Value,
+			state: state,
 		}
+		families.collect(ch, rules, labelNames, reading, metric)
 	}
 	return 1, nil
 }
 
 func (c IPMINativeCollector) Describe(ch chan<- *prometheus.Desc) {
-	ch <- sensorStateDesc
-	ch <- sensorValueDesc
-	ch <- fanSpeedRPMDesc
-	ch <- fanSpeedRatioDesc
-	ch <- fanSpeedStateDesc
-	ch <- temperatureDesc
-	ch <- temperatureStateDesc
-	ch <- voltageDesc
-	ch <- voltageStateDesc
-	ch <- currentDesc
-	ch <- currentStateDesc
-	ch <- powerDesc
-	ch <- powerStateDesc
-}
-
-func collectTypedSensorNative(ch chan<- prometheus.Metric, desc, stateDesc *prometheus.Desc, state float64, data *ipmi.Sensor, scale float64) {
-	ch <- prometheus.MustNewConstMetric(
-		desc,
-		prometheus.GaugeValue,
-		data.Value*scale,
-		strconv.FormatInt(int64(data.Number), 10),
-		data.Name,
-	)
-	ch <- prometheus.MustNewConstMetric(
-		stateDesc,
-		prometheus.GaugeValue,
-		state,
-		strconv.FormatInt(int64(data.Number), 10),
-		data.Name,
-	)
-}
-
-func collectGenericSensorNative(ch chan<- prometheus.Metric, state float64, data *ipmi.Sensor) {
-	ch <- prometheus.MustNewConstMetric(
-		sensorValueNativeDesc,
-		prometheus.GaugeValue,
-		data.Value,
-		strconv.FormatInt(int64(data.Number), 10),
-		data.Name,
-		data.SensorType.String(),
-	)
-	ch <- prometheus.MustNewConstMetric(
-		sensorStateNativeDesc,
-		prometheus.GaugeValue,
-		state,
-		strconv.FormatInt(int64(data.Number), 10),
-		data.Name,
-		data.SensorType.String(),
-	)
+	ipmiNativeSensorFamilies.describe(ch)
 }
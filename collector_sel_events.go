@@ -14,6 +14,8 @@
 package main
 
 import (
+	"context"
+	"strconv"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -47,7 +49,9 @@ var (
 	)
 )
 
-type SELEventsCollector struct{}
+type SELEventsCollector struct {
+	options SELEventsCollectorOptions
+}
 
 func (c SELEventsCollector) Name() CollectorName {
 	return SELEventsCollectorName
@@ -58,7 +62,7 @@ func (c SELEventsCollector) Cmd() string {
 }
 
 func (c SELEventsCollector) Args() []string {
-	return []string{
+	args := []string{
 		"--quiet-cache",
 		"--comma-separated-output",
 		"--no-header-output",
@@ -67,9 +71,13 @@ func (c SELEventsCollector) Args() []string {
 		"--interpret-oem-data",
 		"--entity-sensor-names",
 	}
+	if c.options.MaxEntries > 0 {
+		args = append(args, "--tail="+strconv.Itoa(c.options.MaxEntries))
+	}
+	return args
 }
 
-func (c SELEventsCollector) Collect(result freeipmi.Result, ch chan<- prometheus.Metric, target ipmiTarget) (int, error) {
+func (c SELEventsCollector) Collect(_ context.Context, result freeipmi.Result, ch chan<- prometheus.Metric, target ipmiTarget) (int, error) {
 	selEventConfigs := target.config.SELEvents
 
 	events, err := freeipmi.GetSELEvents(result)
@@ -16,6 +16,7 @@ package main
 import (
 	"context"
 
+	"github.com/bougou/go-ipmi"
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/prometheus-community/ipmi_exporter/freeipmi"
@@ -42,7 +43,12 @@ var (
 	)
 )
 
-type SELEventsNativeCollector struct{}
+// selLastRecordID requests the last entry of the SEL.
+const selLastRecordID = 0xffff
+
+type SELEventsNativeCollector struct {
+	options SELEventsCollectorOptions
+}
 
 func (c SELEventsNativeCollector) Name() CollectorName {
 	// The name is intentionally the same as the non-native collector
@@ -57,16 +63,80 @@ func (c SELEventsNativeCollector) Args() []string {
 	return []string{}
 }
 
-func (c SELEventsNativeCollector) Collect(_ freeipmi.Result, ch chan<- prometheus.Metric, target ipmiTarget) (int, error) {
-	selEventConfigs := target.config.SELEvents
-
-	ctx := context.TODO()
-	client, err := NewNativeClient(ctx, target)
+// readSELEntriesNative reads the SEL via the native IPMI session. If
+// maxEntries is positive, only that many of the most recent entries are
+// returned.
+func readSELEntriesNative(ctx context.Context, target ipmiTarget, maxEntries int) ([]*ipmi.SEL, error) {
+	client, err := target.native.acquire(ctx)
 	if err != nil {
-		return 0, err
+		return nil, err
+	}
+	defer target.native.release()
+	if maxEntries > 0 {
+		res, err := readSELTailNative(ctx, client, target.trace, maxEntries)
+		if err != nil || res != nil {
+			return res, err
+		}
 	}
-	defer CloseNativeClient(ctx, client)
 	res, err := client.GetSELEntries(ctx, 0)
+	target.trace.native("GetSELEntries", res, err)
+	if err != nil {
+		return nil, err
+	}
+	// Entries are returned oldest first, as ipmi-sel --tail would see them.
+	if maxEntries > 0 && len(res) > maxEntries {
+		res = res[len(res)-maxEntries:]
+	}
+	return res, nil
+}
+
+// readSELTailNative reads only the most recent maxEntries entries of the SEL.
+// IPMI can only walk the SEL forward, so reading starts at the record ID the
+// first of them has if record IDs are consecutive, which they usually are. If
+// they are not, or the SEL is not larger than maxEntries, nil is returned and
+// the whole SEL has to be read.
+func readSELTailNative(ctx context.Context, client *ipmi.Client, trace *collectorTrace, maxEntries int) ([]*ipmi.SEL, error) {
+	info, err := client.GetSELInfo(ctx)
+	trace.native("GetSELInfo", info, err)
+	if err != nil {
+		return nil, err
+	}
+	if int(info.Entries) <= maxEntries {
+		return nil, nil
+	}
+	last, err := client.GetSELEntry(ctx, 0, selLastRecordID)
+	trace.native("GetSELEntry", last, err)
+	if err != nil {
+		return nil, err
+	}
+	sel, err := ipmi.ParseSEL(last.Data)
+	if err != nil {
+		return nil, err
+	}
+	if int(sel.RecordID) < maxEntries {
+		return nil, nil
+	}
+	start := sel.RecordID - uint16(maxEntries-1)
+	res, err := client.GetSELEntries(ctx, start)
+	trace.native("GetSELEntries", res, err)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		// No record with that ID, so record IDs are not consecutive.
+		logger.Debug("Reading SEL from guessed record ID failed, reading all entries", "target", client.Host, "record_id", start, "error", err)
+		return nil, nil
+	}
+	if len(res) < maxEntries {
+		return nil, nil
+	}
+	return res[len(res)-maxEntries:], nil
+}
+
+func (c SELEventsNativeCollector) Collect(ctx context.Context, _ freeipmi.Result, ch chan<- prometheus.Metric, target ipmiTarget) (int, error) {
+	selEventConfigs := target.config.SELEvents
+
+	res, err := readSELEntriesNative(ctx, target, c.options.MaxEntries)
 	if err != nil {
 		return 0, err
 	}
@@ -52,14 +52,14 @@ func (c SELNativeCollector) Args() []string {
 	return []string{""}
 }
 
-func (c SELNativeCollector) Collect(_ freeipmi.Result, ch chan<- prometheus.Metric, target ipmiTarget) (int, error) {
-	ctx := context.TODO()
-	client, err := NewNativeClient(ctx, target)
+func (c SELNativeCollector) Collect(ctx context.Context, _ freeipmi.Result, ch chan<- prometheus.Metric, target ipmiTarget) (int, error) {
+	client, err := target.native.acquire(ctx)
 	if err != nil {
 		return 0, err
 	}
-	defer CloseNativeClient(ctx, client)
+	defer target.native.release()
 	res, err := client.GetSELInfo(ctx)
+	target.trace.native("GetSELInfo", res, err)
 	if err != nil {
 		return 0, err
 	}
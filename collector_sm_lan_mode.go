@@ -14,6 +14,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 
@@ -49,7 +50,7 @@ func (c SMLANModeCollector) Args() []string {
 	return []string{"0x0", "0x30", "0x70", "0x0c", "0"}
 }
 
-func (c SMLANModeCollector) Collect(result freeipmi.Result, ch chan<- prometheus.Metric, target ipmiTarget) (int, error) {
+func (c SMLANModeCollector) Collect(_ context.Context, result freeipmi.Result, ch chan<- prometheus.Metric, target ipmiTarget) (int, error) {
 	octets, err := freeipmi.GetRawOctets(result)
 	if err != nil {
 		logger.Error("Failed to collect LAN mode data", "target", targetName(target.host), "error", err)
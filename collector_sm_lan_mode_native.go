@@ -47,18 +47,18 @@ func (c SMLANModeNativeCollector) Args() []string {
 	return []string{}
 }
 
-func (c SMLANModeNativeCollector) Collect(_ freeipmi.Result, ch chan<- prometheus.Metric, target ipmiTarget) (int, error) {
-	ctx := context.TODO()
-	client, err := NewNativeClient(ctx, target)
+func (c SMLANModeNativeCollector) Collect(ctx context.Context, _ freeipmi.Result, ch chan<- prometheus.Metric, target ipmiTarget) (int, error) {
+	client, err := target.native.acquire(ctx)
 	if err != nil {
 		return 0, err
 	}
-	defer CloseNativeClient(ctx, client)
-	if _, err := client.SetSessionPrivilegeLevel(ctx, ipmi.PrivilegeLevelAdministrator); err != nil {
-		logger.Error("Failed to set privilege level to admin", "target", targetName(target.host))
-		return 0, fmt.Errorf("failed to set privilege level to admin")
+	defer target.native.release()
+	if err := target.native.raisePrivilege(ctx, ipmi.PrivilegeLevelAdministrator); err != nil {
+		logger.Error("Failed to set privilege level to admin", "target", targetName(target.host), "error", err)
+		return 0, err
 	}
 	res, err := client.RawCommand(ctx, ipmi.NetFnOEMSupermicroRequest, 0x70, []byte{0x0C, 0x00}, "GetSupermicroLanMode")
+	target.trace.native("GetSupermicroLanMode", res, err)
 	if err != nil {
 		logger.Error("raw command failed", "error", err)
 		return 0, err
@@ -0,0 +1,46 @@
+// Copyright 2025 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestScrapeWithoutCollectorsKeepsBreakerOpen(t *testing.T) {
+	defer func(b *circuitBreaker, l *scrapeLog) {
+		breaker, scrapeHistory = b, l
+	}(breaker, scrapeHistory)
+	breaker = newCircuitBreaker(1, time.Nanosecond, time.Nanosecond)
+	scrapeHistory = newScrapeLog(0)
+
+	breaker.record("bmc", false)
+	// Let the backoff pass, so that the next scrape would be let through.
+	time.Sleep(time.Millisecond)
+
+	collectMetrics(metaCollector{
+		ctx:    context.Background(),
+		target: "bmc",
+		module: "default",
+		config: sc,
+		filter: collectorFilter{exclude: defaultConfig.Collectors},
+	})
+	if !breaker.isOpen("bmc") {
+		t.Error("breaker closed by a scrape that ran no collectors")
+	}
+	if !breaker.allow("bmc") {
+		t.Error("breaker does not let a probe through after a scrape that ran no collectors")
+	}
+}
@@ -14,13 +14,24 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"iter"
+	"maps"
+	"net"
+	"net/netip"
 	"os"
+	"path"
+	"path/filepath"
+	"reflect"
 	"regexp"
+	"slices"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
 	"go.yaml.in/yaml/v2"
 
 	"github.com/prometheus-community/ipmi_exporter/freeipmi"
@@ -43,6 +54,11 @@ func (c ConfiguredCollector) Name() CollectorName {
 }
 
 func (c ConfiguredCollector) Cmd() string {
+	// Native collectors do not run commands, even if one is configured for
+	// the FreeIPMI implementation of the collector.
+	if c.collector.Cmd() == "" {
+		return ""
+	}
 	if c.command != "" {
 		return c.command
 	}
@@ -64,50 +80,52 @@ func (c ConfiguredCollector) Args() []string {
 	return args
 }
 
-func (c ConfiguredCollector) Collect(output freeipmi.Result, ch chan<- prometheus.Metric, target ipmiTarget) (int, error) {
-	return c.collector.Collect(output, ch, target)
+func (c ConfiguredCollector) Collect(ctx context.Context, output freeipmi.Result, ch chan<- prometheus.Metric, target ipmiTarget) (int, error) {
+	return c.collector.Collect(ctx, output, ch, target)
 }
 
-func (c CollectorName) GetInstance() (collector, error) {
+// GetInstance returns the collector with the given name, using either the
+// native or the FreeIPMI implementation, set up with the given options.
+func (c CollectorName) GetInstance(native bool, options CollectorOptions) (collector, error) {
 	// This is where a new collector would have to be "registered"
 	switch c {
 	case IPMICollectorName:
-		if *nativeIPMI {
-			return IPMINativeCollector{}, nil
+		if native {
+			return IPMINativeCollector{options: options.IPMI}, nil
 		}
-		return IPMICollector{}, nil
+		return IPMICollector{options: options.IPMI}, nil
 	case BMCCollectorName:
-		if *nativeIPMI {
+		if native {
 			return BMCNativeCollector{}, nil
 		}
 		return BMCCollector{}, nil
 	case BMCWatchdogCollectorName:
-		if *nativeIPMI {
+		if native {
 			return BMCWatchdogNativeCollector{}, nil
 		}
 		return BMCWatchdogCollector{}, nil
 	case SELCollectorName:
-		if *nativeIPMI {
+		if native {
 			return SELNativeCollector{}, nil
 		}
 		return SELCollector{}, nil
 	case SELEventsCollectorName:
-		if *nativeIPMI {
-			return SELEventsNativeCollector{}, nil
+		if native {
+			return SELEventsNativeCollector{options: options.SELEvents}, nil
 		}
-		return SELEventsCollector{}, nil
+		return SELEventsCollector{options: options.SELEvents}, nil
 	case DCMICollectorName:
-		if *nativeIPMI {
+		if native {
 			return DCMINativeCollector{}, nil
 		}
 		return DCMICollector{}, nil
 	case ChassisCollectorName:
-		if *nativeIPMI {
+		if native {
 			return ChassisNativeCollector{}, nil
 		}
 		return ChassisCollector{}, nil
 	case SMLANModeCollectorName:
-		if *nativeIPMI {
+		if native {
 			return SMLANModeNativeCollector{}, nil
 		}
 		return SMLANModeCollector{}, nil
@@ -116,16 +134,21 @@ func (c CollectorName) GetInstance() (collector, error) {
 }
 
 func (c CollectorName) IsValid() error {
-	_, err := c.GetInstance()
+	_, err := c.GetInstance(false, CollectorOptions{})
 	return err
 }
 
 // Config is the Go representation of the yaml config file.
 type Config struct {
-	Modules map[string]IPMIConfig `yaml:"modules"`
+	Modules     map[string]IPMIConfig `yaml:"modules"`
+	PollTargets []PollTarget          `yaml:"poll_targets,omitempty"`
+	Targets     []TargetRule          `yaml:"targets,omitempty"`
 
 	// Catches all undefined fields and must be empty after parsing.
 	XXX map[string]any `yaml:",inline"`
+
+	// hash is the hash of the content the config was loaded from.
+	hash string
 }
 
 // SafeConfig wraps Config for concurrency-safe operations.
@@ -137,11 +160,17 @@ type SafeConfig struct {
 // IPMIConfig is the Go representation of a module configuration in the yaml
 // config file.
 type IPMIConfig struct {
+	Extends          string                     `yaml:"extends,omitempty"`
 	User             string                     `yaml:"user"`
 	Password         string                     `yaml:"pass"`
+	PasswordFile     string                     `yaml:"pass_file,omitempty"`
+	PasswordCred     string                     `yaml:"pass_credential,omitempty"`
+	PasswordEnv      string                     `yaml:"pass_env,omitempty"`
 	Privilege        string                     `yaml:"privilege"`
 	Driver           string                     `yaml:"driver"`
 	Timeout          uint32                     `yaml:"timeout"`
+	Backend          string                     `yaml:"backend,omitempty"`
+	CollectorBackend map[CollectorName]string   `yaml:"collector_backend,omitempty"`
 	Collectors       []CollectorName            `yaml:"collectors"`
 	ExcludeSensorIDs []int64                    `yaml:"exclude_sensor_ids"`
 	WorkaroundFlags  []string                   `yaml:"workaround_flags"`
@@ -149,11 +178,315 @@ type IPMIConfig struct {
 	CollectorArgs    map[CollectorName][]string `yaml:"default_args"`
 	CustomArgs       map[CollectorName][]string `yaml:"custom_args"`
 
-	SELEvents []*IpmiSELEvent `yaml:"sel_events,omitempty"`
+	// CollectorConcurrency limits how many collectors of this module run at
+	// the same time. Zero (the default) means no limit.
+	CollectorConcurrency int `yaml:"collector_concurrency"`
+
+	CollectorOptions CollectorOptions `yaml:"collector_options,omitempty"`
+
+	SELEvents   []*IpmiSELEvent `yaml:"sel_events,omitempty"`
+	SensorRules []SensorRule    `yaml:"sensor_rules,omitempty"`
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]any `yaml:",inline"`
+}
+
+// CollectorOptions holds the settings of individual collectors. They apply to
+// both the FreeIPMI and the native implementation of a collector, as far as
+// the latter supports them.
+type CollectorOptions struct {
+	IPMI      IPMICollectorOptions      `yaml:"ipmi,omitempty"`
+	SELEvents SELEventsCollectorOptions `yaml:"sel-events,omitempty"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]any `yaml:",inline"`
+}
+
+// IPMICollectorOptions are the settings of the ipmi collector.
+type IPMICollectorOptions struct {
+	BridgeSensors    bool   `yaml:"bridge_sensors,omitempty"`
+	InterpretOEMData bool   `yaml:"interpret_oem_data,omitempty"`
+	SharedSensors    bool   `yaml:"shared_sensors,omitempty"`
+	SDRCacheDir      string `yaml:"sdr_cache_dir,omitempty"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]any `yaml:",inline"`
+}
+
+// set returns true if any of the options is set.
+func (o IPMICollectorOptions) set() bool {
+	return o.BridgeSensors || o.InterpretOEMData || o.SharedSensors || o.SDRCacheDir != ""
+}
+
+// unsupportedNative returns the names of the options that are set but have no
+// equivalent in the native implementation.
+func (o IPMICollectorOptions) unsupportedNative() []string {
+	var names []string
+	if o.BridgeSensors {
+		names = append(names, "bridge_sensors")
+	}
+	if o.InterpretOEMData {
+		names = append(names, "interpret_oem_data")
+	}
+	if o.SharedSensors {
+		names = append(names, "shared_sensors")
+	}
+	if o.SDRCacheDir != "" {
+		names = append(names, "sdr_cache_dir")
+	}
+	return names
+}
+
+// SELEventsCollectorOptions are the settings of the sel-events collector.
+type SELEventsCollectorOptions struct {
+	// MaxEntries limits the collector to the most recent SEL entries. Zero
+	// means no limit.
+	MaxEntries int `yaml:"max_entries,omitempty"`
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]any `yaml:",inline"`
+}
+
+// validate checks the collector options.
+func (s *CollectorOptions) validate() error {
+	for field, xxx := range map[string]map[string]any{
+		"collector_options":            s.XXX,
+		"collector_options.ipmi":       s.IPMI.XXX,
+		"collector_options.sel-events": s.SELEvents.XXX,
+	} {
+		if err := checkOverflow(xxx, field); err != nil {
+			return err
+		}
+	}
+	if s.SELEvents.MaxEntries < 0 {
+		return fmt.Errorf("collector_options.sel-events.max_entries: must not be negative")
+	}
+	return nil
+}
+
+// PollTarget is a target that is polled in the background. Scrapes of it are
+// answered from the result of the latest poll.
+type PollTarget struct {
+	Target   string            `yaml:"target"`
+	Module   string            `yaml:"module"`
+	Interval model.Duration    `yaml:"interval"`
+	Jitter   model.Duration    `yaml:"jitter"`
+	Labels   map[string]string `yaml:"labels,omitempty"`
+}
+
+var defaultPollTarget = PollTarget{
+	Interval: model.Duration(time.Minute),
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (s *PollTarget) UnmarshalYAML(unmarshal func(any) error) error {
+	*s = defaultPollTarget
+	type plain PollTarget
+	if err := unmarshal((*plain)(s)); err != nil {
+		return err
+	}
+	if s.Target == "" {
+		return fmt.Errorf("poll target without target")
+	}
+	if s.Interval <= 0 {
+		return fmt.Errorf("poll target %s: interval must be positive", s.Target)
+	}
+	if s.Jitter < 0 || s.Jitter > s.Interval {
+		return fmt.Errorf("poll target %s: jitter must be between 0 and the interval", s.Target)
+	}
+	if err := validateTargetLabels(s.Labels); err != nil {
+		return fmt.Errorf("poll target %s: %w", s.Target, err)
+	}
+	return nil
+}
+
+// validateTargetLabels checks the labels of a target for service discovery.
+// The module label is set by the exporter, and labels starting with "__" are
+// reserved for Prometheus.
+func validateTargetLabels(labels map[string]string) error {
+	for k := range labels {
+		if !model.LabelName(k).IsValid() || strings.HasPrefix(k, model.ReservedLabelPrefix) || k == "module" {
+			return fmt.Errorf("labels: invalid label name %q", k)
+		}
+	}
+	return nil
+}
+
+// TargetRule selects the module and credentials for all targets matching it.
+// Match is either a host name or IP address, a glob pattern or a CIDR range.
+type TargetRule struct {
+	Match        string `yaml:"match"`
+	Module       string `yaml:"module,omitempty"`
+	User         string `yaml:"user,omitempty"`
+	Password     string `yaml:"pass,omitempty"`
+	PasswordFile string `yaml:"pass_file,omitempty"`
+	PasswordCred string `yaml:"pass_credential,omitempty"`
+	PasswordEnv  string `yaml:"pass_env,omitempty"`
+	Privilege    string `yaml:"privilege,omitempty"`
+	// Labels are attached to the target in service discovery.
+	Labels map[string]string `yaml:"labels,omitempty"`
+
+	prefix netip.Prefix
+	glob   bool
+
 	// Catches all undefined fields and must be empty after parsing.
 	XXX map[string]any `yaml:",inline"`
 }
 
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (s *TargetRule) UnmarshalYAML(unmarshal func(any) error) error {
+	type plain TargetRule
+	if err := unmarshal((*plain)(s)); err != nil {
+		return err
+	}
+	if err := checkOverflow(s.XXX, "targets"); err != nil {
+		return err
+	}
+	switch {
+	case s.Match == "":
+		return fmt.Errorf("target rule without match")
+	case strings.Contains(s.Match, "/"):
+		prefix, err := netip.ParsePrefix(s.Match)
+		if err != nil {
+			return fmt.Errorf("target rule %s: %w", s.Match, err)
+		}
+		s.prefix = prefix.Masked()
+	case strings.ContainsAny(s.Match, "*?["):
+		if _, err := path.Match(s.Match, ""); err != nil {
+			return fmt.Errorf("target rule %s: %w", s.Match, err)
+		}
+		s.glob = true
+	}
+	if err := validateSetting("privilege", s.Privilege, validPrivileges); err != nil {
+		return fmt.Errorf("target rule %s: %w", s.Match, err)
+	}
+	if err := validateTargetLabels(s.Labels); err != nil {
+		return fmt.Errorf("target rule %s: %w", s.Match, err)
+	}
+	return nil
+}
+
+// exact returns true if the rule matches a single host name or IP address.
+func (s *TargetRule) exact() bool {
+	return !s.prefix.IsValid() && !s.glob
+}
+
+// Matches returns true if the rule applies to the given target.
+func (s *TargetRule) Matches(target string) bool {
+	// Targets may include the port, e.g. 10.1.3.5:623.
+	host := target
+	if h, _, err := net.SplitHostPort(target); err == nil {
+		host = h
+	}
+	switch {
+	case s.prefix.IsValid():
+		addr, err := netip.ParseAddr(host)
+		return err == nil && s.prefix.Contains(addr.Unmap())
+	case s.glob:
+		ok, _ := path.Match(strings.ToLower(s.Match), strings.ToLower(target))
+		if !ok {
+			ok, _ = path.Match(strings.ToLower(s.Match), strings.ToLower(host))
+		}
+		return ok
+	default:
+		return strings.EqualFold(s.Match, target) || strings.EqualFold(s.Match, host)
+	}
+}
+
+// apply returns the config with the credentials overridden by the rule.
+func (s *TargetRule) apply(config IPMIConfig) IPMIConfig {
+	if s.User != "" {
+		config.User = s.User
+	}
+	if s.Password != "" {
+		config.Password = s.Password
+	}
+	if s.Privilege != "" {
+		config.Privilege = s.Privilege
+	}
+	return config
+}
+
+// SensorRule changes how matching sensors are exported. Name, Type and Unit
+// are regular expressions matched against the whole respective property of a
+// sensor. Empty ones match any sensor.
+type SensorRule struct {
+	Name   string            `yaml:"name,omitempty"`
+	Type   string            `yaml:"type,omitempty"`
+	Unit   string            `yaml:"unit,omitempty"`
+	Drop   bool              `yaml:"drop,omitempty"`
+	Rename string            `yaml:"rename,omitempty"`
+	Metric string            `yaml:"metric,omitempty"`
+	Labels map[string]string `yaml:"labels,omitempty"`
+
+	nameRegex, typeRegex, unitRegex *regexp.Regexp
+
+	// Catches all undefined fields and must be empty after parsing.
+	XXX map[string]any `yaml:",inline"`
+}
+
+// UnmarshalYAML implements the yaml.Unmarshaler interface.
+func (s *SensorRule) UnmarshalYAML(unmarshal func(any) error) error {
+	type plain SensorRule
+	if err := unmarshal((*plain)(s)); err != nil {
+		return err
+	}
+	return checkOverflow(s.XXX, "sensor_rules")
+}
+
+// compile checks the rule and compiles its regular expressions.
+func (s *SensorRule) compile() error {
+	for field, re := range map[string]struct {
+		raw      string
+		compiled **regexp.Regexp
+	}{
+		"name": {s.Name, &s.nameRegex},
+		"type": {s.Type, &s.typeRegex},
+		"unit": {s.Unit, &s.unitRegex},
+	} {
+		if re.raw == "" {
+			continue
+		}
+		if _, err := regexp.Compile(re.raw); err != nil {
+			return fmt.Errorf("%s: %w", field, err)
+		}
+		*re.compiled = regexp.MustCompile("^(?:" + re.raw + ")$")
+	}
+	if s.Metric != "" {
+		if _, ok := sensorMetricSpecs[sensorMetric(s.Metric)]; !ok {
+			valid := make([]string, 0, len(sensorMetricSpecs))
+			for m := range sensorMetricSpecs {
+				valid = append(valid, string(m))
+			}
+			slices.Sort(valid)
+			return fmt.Errorf("metric: invalid value %q, must be one of %s", s.Metric, strings.Join(valid, ", "))
+		}
+	}
+	for k := range s.Labels {
+		if !model.LabelName(k).IsValid() || slices.Contains([]string{"id", "name", "type"}, k) {
+			return fmt.Errorf("labels: invalid label name %q", k)
+		}
+	}
+	if s.Drop && (s.Rename != "" || s.Metric != "" || len(s.Labels) > 0) {
+		return fmt.Errorf("drop cannot be combined with other actions")
+	}
+	return nil
+}
+
+func (s *SensorRule) matches(name, typ, unit string) bool {
+	return (s.nameRegex == nil || s.nameRegex.MatchString(name)) &&
+		(s.typeRegex == nil || s.typeRegex.MatchString(typ)) &&
+		(s.unitRegex == nil || s.unitRegex.MatchString(unit))
+}
+
+// rename returns the new name of a sensor. References to capture groups of
+// the name regular expression, like $1, are expanded.
+func (s *SensorRule) rename(name string) string {
+	if s.nameRegex == nil {
+		return s.Rename
+	}
+	return s.nameRegex.ReplaceAllString(name, s.Rename)
+}
+
 type IpmiSELEvent struct {
 	Name     string         `yaml:"name"`
 	RegexRaw string         `yaml:"regex"`
@@ -184,9 +517,259 @@ func (s *Config) UnmarshalYAML(unmarshal func(any) error) error {
 	if err := checkOverflow(s.XXX, "config"); err != nil {
 		return err
 	}
+	var raw struct {
+		Modules map[string]map[any]any `yaml:"modules"`
+	}
+	if err := unmarshal(&raw); err != nil {
+		return err
+	}
+	for name, m := range s.Modules {
+		if m.Extends == "" {
+			continue
+		}
+		merged, err := mergeModule(raw.Modules, name, nil)
+		if err != nil {
+			return err
+		}
+		b, err := yaml.Marshal(merged)
+		if err != nil {
+			return fmt.Errorf("modules.%s: %w", name, err)
+		}
+		m = IPMIConfig{}
+		if err := yaml.Unmarshal(b, &m); err != nil {
+			return fmt.Errorf("modules.%s: %w", name, err)
+		}
+		s.Modules[name] = m
+	}
+	for name, m := range s.Modules {
+		if err := m.validate(); err != nil {
+			return fmt.Errorf("modules.%s: %w", name, err)
+		}
+		if m.UseNative(IPMICollectorName) {
+			if unsupported := m.CollectorOptions.IPMI.unsupportedNative(); len(unsupported) > 0 {
+				logger.Warn("Options not supported by the native ipmi collector are ignored", "module", name, "options", strings.Join(unsupported, ", "))
+			}
+		}
+		pass, err := resolvePassword(m.Password, m.PasswordFile, m.PasswordCred, m.PasswordEnv)
+		if err != nil {
+			return fmt.Errorf("modules.%s: %w", name, err)
+		}
+		m.Password = pass
+		s.Modules[name] = m
+	}
+	for i, r := range s.Targets {
+		if _, ok := s.Modules[r.Module]; !ok && r.Module != "" && r.Module != "default" {
+			return fmt.Errorf("target rule %s: unknown module %q", r.Match, r.Module)
+		}
+		pass, err := resolvePassword(r.Password, r.PasswordFile, r.PasswordCred, r.PasswordEnv)
+		if err != nil {
+			return fmt.Errorf("target rule %s: %w", r.Match, err)
+		}
+		s.Targets[i].Password = pass
+	}
+	for i, t := range s.PollTargets {
+		if t.Module == "" {
+			s.PollTargets[i].Module = s.moduleForTarget(t.Target)
+			continue
+		}
+		if _, ok := s.Modules[t.Module]; !ok && t.Module != "default" {
+			return fmt.Errorf("poll target %s: unknown module %q", t.Target, t.Module)
+		}
+	}
 	return nil
 }
 
+// passwordKeys are the settings a password can be configured with. Only one
+// of them may be set, so a module setting any of them replaces all of them.
+var passwordKeys = []string{"pass", "pass_file", "pass_credential", "pass_env"}
+
+// dedupKeys are the module settings whose lists drop duplicates when merged.
+var dedupKeys = []any{"collectors", "exclude_sensor_ids"}
+
+// mergeModule returns the raw settings of a module merged with those of the
+// modules it extends. Maps are merged recursively, lists are concatenated
+// (dropping duplicates only for dedupKeys) and other values are replaced.
+// Defaults apply to the module at the root of the chain. chain lists the
+// modules already visited.
+func mergeModule(modules map[string]map[any]any, name string, chain []string) (map[any]any, error) {
+	chain = append(chain, name)
+	if slices.Contains(chain[:len(chain)-1], name) {
+		return nil, fmt.Errorf("modules.%s: extends: cycle %s", chain[0], strings.Join(chain, " -> "))
+	}
+	module := modules[name]
+	parent, ok := module["extends"].(string)
+	if !ok || parent == "" {
+		return withDefaults(module), nil
+	}
+	if _, ok := modules[parent]; !ok {
+		return nil, fmt.Errorf("modules.%s: extends: unknown module %q", name, parent)
+	}
+	base, err := mergeModule(modules, parent, chain)
+	if err != nil {
+		return nil, err
+	}
+	base = maps.Clone(base)
+	for _, key := range passwordKeys {
+		if _, ok := module[key]; ok {
+			for _, k := range passwordKeys {
+				delete(base, k)
+			}
+			break
+		}
+	}
+	for k, v := range module {
+		base[k] = mergeValues(base[k], v, slices.Contains(dedupKeys, k))
+	}
+	return base, nil
+}
+
+// withDefaults returns the raw settings of a module with the default
+// collectors added, unless the module sets them.
+func withDefaults(module map[any]any) map[any]any {
+	if _, ok := module["collectors"]; ok {
+		return module
+	}
+	module = maps.Clone(module)
+	collectors := make([]any, 0, len(defaultConfig.Collectors))
+	for _, c := range defaultConfig.Collectors {
+		collectors = append(collectors, string(c))
+	}
+	module["collectors"] = collectors
+	return module
+}
+
+func mergeValues(base, override any, dedup bool) any {
+	switch o := override.(type) {
+	case map[any]any:
+		b, ok := base.(map[any]any)
+		if !ok {
+			return o
+		}
+		result := maps.Clone(b)
+		for k, v := range o {
+			result[k] = mergeValues(b[k], v, false)
+		}
+		return result
+	case []any:
+		b, ok := base.([]any)
+		if !ok {
+			return o
+		}
+		result := slices.Clone(b)
+		for _, v := range o {
+			if !dedup || !slices.ContainsFunc(result, func(e any) bool { return reflect.DeepEqual(e, v) }) {
+				result = append(result, v)
+			}
+		}
+		return result
+	default:
+		return o
+	}
+}
+
+var envVarRegexp = regexp.MustCompile(`\$\$|\$\{(\w+)\}`)
+
+// expandEnv expands references to environment variables in the form ${VAR}.
+// A literal $ is written as $$.
+func expandEnv(s string) (string, error) {
+	var err error
+	s = envVarRegexp.ReplaceAllStringFunc(s, func(ref string) string {
+		if ref == "$$" {
+			return "$"
+		}
+		name := envVarRegexp.FindStringSubmatch(ref)[1]
+		value, ok := os.LookupEnv(name)
+		if !ok && err == nil {
+			err = fmt.Errorf("environment variable %s is not set", name)
+		}
+		return value
+	})
+	return s, err
+}
+
+// resolvePassword returns the password configured via one of pass, pass_file,
+// pass_credential or pass_env. References to environment variables are
+// expanded in pass_file and pass_credential, pass is taken literally.
+func resolvePassword(pass, file, credential, env string) (string, error) {
+	set := 0
+	for _, v := range []string{pass, file, credential, env} {
+		if v != "" {
+			set++
+		}
+	}
+	if set > 1 {
+		return "", fmt.Errorf("only one of pass, pass_file, pass_credential and pass_env may be set")
+	}
+
+	var err error
+	switch {
+	case file != "":
+		file, err = expandEnv(file)
+		if err != nil {
+			return "", fmt.Errorf("pass_file: %w", err)
+		}
+		pass, err = readSecretFile(file)
+		if err != nil {
+			return "", fmt.Errorf("pass_file: %w", err)
+		}
+		return pass, nil
+	case credential != "":
+		credential, err = expandEnv(credential)
+		if err != nil {
+			return "", fmt.Errorf("pass_credential: %w", err)
+		}
+		dir := os.Getenv("CREDENTIALS_DIRECTORY")
+		if dir == "" {
+			return "", fmt.Errorf("pass_credential: %s: CREDENTIALS_DIRECTORY is not set", credential)
+		}
+		pass, err = readSecretFile(filepath.Join(dir, credential))
+		if err != nil {
+			return "", fmt.Errorf("pass_credential: %w", err)
+		}
+		return pass, nil
+	case env != "":
+		pass, ok := os.LookupEnv(env)
+		if !ok {
+			return "", fmt.Errorf("pass_env: environment variable %s is not set", env)
+		}
+		return pass, nil
+	default:
+		return pass, nil
+	}
+}
+
+// readSecretFile reads a password from a file, ignoring a trailing newline.
+func readSecretFile(file string) (string, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+// targetRule returns the first rule matching the target, or nil. Rules never
+// apply to local scrapes.
+func (s *Config) targetRule(target string) *TargetRule {
+	if target == targetLocal {
+		return nil
+	}
+	for i := range s.Targets {
+		if s.Targets[i].Matches(target) {
+			return &s.Targets[i]
+		}
+	}
+	return nil
+}
+
+// moduleForTarget returns the module selected for the target by the target
+// rules, or "default".
+func (s *Config) moduleForTarget(target string) string {
+	if r := s.targetRule(target); r != nil && r.Module != "" {
+		return r.Module
+	}
+	return "default"
+}
+
 // UnmarshalYAML implements the yaml.Unmarshaler interface.
 func (s *IPMIConfig) UnmarshalYAML(unmarshal func(any) error) error {
 	*s = defaultConfig
@@ -194,36 +777,153 @@ func (s *IPMIConfig) UnmarshalYAML(unmarshal func(any) error) error {
 	if err := unmarshal((*plain)(s)); err != nil {
 		return err
 	}
-	if err := checkOverflow(s.XXX, "modules"); err != nil {
-		return err
+	return checkOverflow(s.XXX, "modules")
+}
+
+// Backends the collectors can use.
+const (
+	backendNative   = "native"
+	backendFreeipmi = "freeipmi"
+)
+
+// Valid values of the privilege and driver settings, see freeipmi.conf(5).
+var (
+	validPrivileges = []string{"user", "operator", "admin"}
+	validDrivers    = []string{"lan", "lan_2_0", "kcs", "ssif", "openipmi", "sunbmc", "inteldcmi"}
+	validBackends   = []string{backendNative, backendFreeipmi}
+
+	// validWorkaroundFlags are the workaround flags supported by any of the
+	// FreeIPMI tools used by the exporter.
+	validWorkaroundFlags = []string{
+		"none", "authcap", "nochecksumcheck", "idzero", "unexpectedauth",
+		"forcepermsg", "endianseq", "noauthcodecheck", "intel20",
+		"supermicro20", "sun20", "opensesspriv", "integritycheckvalue",
+		"assumeio", "assumemaxsdrrecordcount", "solpayloadsize", "solport",
+		"solstatus", "serialalertsdeferred", "solpacketseq", "ignorestateflag",
+		"malformedack", "guidformat", "ipmiping", "skipchecks", "slowcommit",
+		"veryslowcommit", "discretereading", "ignorescanningdisabled",
+		"assumebmcowner", "ignoreauthcode", "assumesystemevent",
 	}
-	for _, c := range s.Collectors {
+)
+
+// validateSetting returns an error if value is set but not one of valid,
+// ignoring case.
+func validateSetting(field, value string, valid []string) error {
+	if value == "" || slices.Contains(valid, strings.ToLower(value)) {
+		return nil
+	}
+	return fmt.Errorf("%s: invalid value %q, must be one of %s", field, value, strings.Join(valid, ", "))
+}
+
+// validate checks the semantics of the module config and compiles its
+// regular expressions. Errors name the offending field.
+func (s *IPMIConfig) validate() error {
+	for i, c := range s.Collectors {
 		if err := c.IsValid(); err != nil {
+			return fmt.Errorf("collectors[%d]: %w", i, err)
+		}
+	}
+	for field, keys := range map[string]iter.Seq[CollectorName]{
+		"collector_backend": maps.Keys(s.CollectorBackend),
+		"collector_cmd":     maps.Keys(s.CollectorCmd),
+		"default_args":      maps.Keys(s.CollectorArgs),
+		"custom_args":       maps.Keys(s.CustomArgs),
+	} {
+		for c := range keys {
+			if err := c.IsValid(); err != nil {
+				return fmt.Errorf("%s: %w", field, err)
+			}
+		}
+	}
+	if err := validateSetting("privilege", s.Privilege, validPrivileges); err != nil {
+		return err
+	}
+	if err := validateSetting("backend", s.Backend, validBackends); err != nil {
+		return err
+	}
+	for c, backend := range s.CollectorBackend {
+		if err := validateSetting(fmt.Sprintf("collector_backend.%s", c), backend, validBackends); err != nil {
+			return err
+		}
+	}
+	if err := validateSetting("driver", s.Driver, validDrivers); err != nil {
+		return err
+	}
+	for i, flag := range s.WorkaroundFlags {
+		if err := validateSetting(fmt.Sprintf("workaround_flags[%d]", i), flag, validWorkaroundFlags); err != nil {
 			return err
 		}
 	}
-	for _, selEvent := range s.SELEvents {
-		selEvent.Regex = regexp.MustCompile(selEvent.RegexRaw)
+	if s.CollectorConcurrency < 0 {
+		return fmt.Errorf("collector_concurrency: must not be negative")
+	}
+	if err := s.CollectorOptions.validate(); err != nil {
+		return err
+	}
+	// default_args replace the arguments the options of the FreeIPMI tools
+	// are translated to.
+	if s.CollectorOptions.IPMI.set() && !s.UseNative(IPMICollectorName) {
+		if _, ok := s.CollectorArgs[IPMICollectorName]; ok {
+			return fmt.Errorf("collector_options.ipmi: cannot be combined with default_args.ipmi")
+		}
+	}
+	if s.CollectorOptions.SELEvents.MaxEntries > 0 && !s.UseNative(SELEventsCollectorName) {
+		if _, ok := s.CollectorArgs[SELEventsCollectorName]; ok {
+			return fmt.Errorf("collector_options.sel-events: cannot be combined with default_args.sel-events")
+		}
+	}
+	for i, selEvent := range s.SELEvents {
+		if selEvent.Name == "" {
+			return fmt.Errorf("sel_events[%d].name: must not be empty", i)
+		}
+		re, err := regexp.Compile(selEvent.RegexRaw)
+		if err != nil {
+			return fmt.Errorf("sel_events[%d].regex: %w", i, err)
+		}
+		selEvent.Regex = re
+	}
+	for i := range s.SensorRules {
+		if err := s.SensorRules[i].compile(); err != nil {
+			return fmt.Errorf("sensor_rules[%d].%w", i, err)
+		}
 	}
 	return nil
 }
 
+// UseNative returns true if the given collector uses the native IPMI
+// implementation: as configured for the collector, or else for the module, or
+// else as set on the command line.
+func (s *IPMIConfig) UseNative(c CollectorName) bool {
+	if backend, ok := s.CollectorBackend[c]; ok {
+		return strings.EqualFold(backend, backendNative)
+	}
+	if s.Backend != "" {
+		return strings.EqualFold(s.Backend, backendNative)
+	}
+	return *nativeIPMI
+}
+
 func (s *IPMIConfig) GetCollectors() []collector {
 	result := []collector{}
 	for _, co := range s.Collectors {
-		// At this point validity has already been checked
-		i, _ := co.GetInstance()
-		cc := ConfiguredCollector{
-			collector:   i,
-			command:     s.CollectorCmd[i.Name()],
-			defaultArgs: s.CollectorArgs[i.Name()],
-			customArgs:  s.CustomArgs[i.Name()],
-		}
-		result = append(result, cc)
+		result = append(result, s.GetCollector(co))
 	}
 	return result
 }
 
+// GetCollector returns the collector with the given name, set up as
+// configured in the module. The collector does not need to be enabled in the
+// module, but its name must be valid.
+func (s *IPMIConfig) GetCollector(co CollectorName) collector {
+	i, _ := co.GetInstance(s.UseNative(co), s.CollectorOptions)
+	return ConfiguredCollector{
+		collector:   i,
+		command:     s.CollectorCmd[i.Name()],
+		defaultArgs: s.CollectorArgs[i.Name()],
+		customArgs:  s.CustomArgs[i.Name()],
+	}
+}
+
 func (s *IPMIConfig) GetFreeipmiConfig() string {
 	var b strings.Builder
 	if s.Driver != "" {
@@ -251,24 +951,176 @@ func (s *IPMIConfig) GetFreeipmiConfig() string {
 	return b.String()
 }
 
-// ReloadConfig reloads the config in a concurrency-safe way. If the configFile
-// is unreadable or unparsable, an error is returned and the old config is kept.
-func (sc *SafeConfig) ReloadConfig(configFile string) error {
+// LoadConfig reads and validates a config file. configFile may also be a
+// directory or a glob pattern, in which case all matching files are merged.
+// An empty configFile results in the default config.
+func LoadConfig(configFile string) (*Config, error) {
 	var c = &Config{}
-	var config []byte
-	var err error
 
-	if configFile != "" {
-		config, err = os.ReadFile(configFile)
+	if configFile == "" {
+		if err := yaml.Unmarshal([]byte("# use empty file as default"), c); err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
+
+	// Errors are logged by the caller.
+	fragments, err := readConfigFiles(configFile)
+	if err != nil {
+		return nil, err
+	}
+	config := fragments[0].content
+	if len(fragments) > 1 {
+		if config, err = mergeConfigFiles(fragments); err != nil {
+			return nil, err
+		}
+	}
+	if err = yaml.Unmarshal(config, c); err != nil {
+		return nil, err
+	}
+	c.hash = hashConfigFiles(fragments)
+	return c, nil
+}
+
+// configFragment is the content of one of the files making up the config.
+type configFragment struct {
+	path    string
+	content []byte
+}
+
+// readConfigFiles reads the config file, or all YAML files in a directory,
+// or all files matching a glob pattern, sorted by name.
+func readConfigFiles(configFile string) ([]configFragment, error) {
+	var paths []string
+	if info, err := os.Stat(configFile); err == nil && info.IsDir() {
+		for _, ext := range []string{"*.yml", "*.yaml"} {
+			matches, err := filepath.Glob(filepath.Join(configFile, ext))
+			if err != nil {
+				return nil, err
+			}
+			paths = append(paths, matches...)
+		}
+	} else if strings.ContainsAny(configFile, "*?[") {
+		matches, err := filepath.Glob(configFile)
 		if err != nil {
-			logger.Error("Error reading config file", "error", err)
-			return err
+			return nil, err
 		}
+		paths = matches
 	} else {
-		config = []byte("# use empty file as default")
+		paths = []string{configFile}
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no config files found in %s", configFile)
 	}
+	slices.Sort(paths)
 
-	if err = yaml.Unmarshal(config, c); err != nil {
+	fragments := make([]configFragment, 0, len(paths))
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		fragments = append(fragments, configFragment{path: path, content: content})
+	}
+	return fragments, nil
+}
+
+// mergeConfigFiles merges config fragments into a single config. Modules may
+// only be defined once, lists are concatenated in the order of the files.
+func mergeConfigFiles(fragments []configFragment) ([]byte, error) {
+	merged := map[string]any{}
+	modules := map[any]any{}
+	definedIn := map[any]string{}
+	for _, f := range fragments {
+		var raw map[string]any
+		if err := yaml.Unmarshal(f.content, &raw); err != nil {
+			return nil, fmt.Errorf("%s: %w", f.path, err)
+		}
+		for key, value := range raw {
+			if m, ok := value.(map[any]any); ok && key == "modules" {
+				for name, module := range m {
+					if other, ok := definedIn[name]; ok {
+						return nil, fmt.Errorf("module %v is defined in both %s and %s", name, other, f.path)
+					}
+					definedIn[name] = f.path
+					modules[name] = module
+				}
+				continue
+			}
+			switch v := value.(type) {
+			case nil:
+			case []any:
+				list, _ := merged[key].([]any)
+				merged[key] = append(list, v...)
+			default:
+				if _, ok := merged[key]; ok {
+					return nil, fmt.Errorf("%s: %s is already set in another file", f.path, key)
+				}
+				merged[key] = v
+			}
+		}
+	}
+	if len(modules) > 0 {
+		merged["modules"] = modules
+	}
+	return yaml.Marshal(merged)
+}
+
+// hashConfigFiles returns a hash of the config. For a single file, it is the
+// hash of its content.
+func hashConfigFiles(fragments []configFragment) string {
+	if len(fragments) == 1 {
+		return configHash(fragments[0].content)
+	}
+	var all []byte
+	for _, f := range fragments {
+		all = append(all, f.path...)
+		all = append(all, 0)
+		all = append(all, f.content...)
+		all = append(all, 0)
+	}
+	return configHash(all)
+}
+
+// Redacted returns a copy of the config with all passwords replaced.
+func (s *Config) Redacted() *Config {
+	c := *s
+	c.Modules = make(map[string]IPMIConfig, len(s.Modules))
+	for name, m := range s.Modules {
+		c.Modules[name] = m.Redacted()
+	}
+	c.Targets = slices.Clone(s.Targets)
+	for i := range c.Targets {
+		c.Targets[i].Password = redact(c.Targets[i].Password)
+		c.Targets[i].PasswordFile = redact(c.Targets[i].PasswordFile)
+		c.Targets[i].PasswordCred = redact(c.Targets[i].PasswordCred)
+		c.Targets[i].PasswordEnv = redact(c.Targets[i].PasswordEnv)
+	}
+	return &c
+}
+
+// Redacted returns a copy of the module config with the password and any
+// references to it replaced.
+func (s IPMIConfig) Redacted() IPMIConfig {
+	s.Password = redact(s.Password)
+	s.PasswordFile = redact(s.PasswordFile)
+	s.PasswordCred = redact(s.PasswordCred)
+	s.PasswordEnv = redact(s.PasswordEnv)
+	return s
+}
+
+func redact(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return "<secret>"
+}
+
+// ReloadConfig reloads the config in a concurrency-safe way. If the configFile
+// is unreadable or unparsable, an error is returned and the old config is kept.
+func (sc *SafeConfig) ReloadConfig(configFile string) error {
+	c, err := LoadConfig(configFile)
+	if err != nil {
 		return err
 	}
 
@@ -282,6 +1134,22 @@ func (sc *SafeConfig) ReloadConfig(configFile string) error {
 	return nil
 }
 
+// Redacted returns a copy of the loaded config with all secrets redacted. It
+// is concurrency-safe.
+func (sc *SafeConfig) Redacted() *Config {
+	sc.Lock()
+	defer sc.Unlock()
+	return sc.C.Redacted()
+}
+
+// Hash returns the hash of the content of the loaded config file. It is
+// concurrency-safe.
+func (sc *SafeConfig) Hash() string {
+	sc.Lock()
+	defer sc.Unlock()
+	return sc.C.hash
+}
+
 // HasModule returns true if a given module is configured. It is concurrency-safe.
 func (sc *SafeConfig) HasModule(module string) bool {
 	sc.Lock()
@@ -291,8 +1159,33 @@ func (sc *SafeConfig) HasModule(module string) bool {
 	return ok
 }
 
+// ModuleNames returns the names of all configured modules, sorted. It is
+// concurrency-safe.
+func (sc *SafeConfig) ModuleNames() []string {
+	sc.Lock()
+	defer sc.Unlock()
+	return slices.Sorted(maps.Keys(sc.C.Modules))
+}
+
+// PollTargets returns the targets to be polled in the background. It is
+// concurrency-safe.
+func (sc *SafeConfig) PollTargets() []PollTarget {
+	sc.Lock()
+	defer sc.Unlock()
+	return sc.C.PollTargets
+}
+
+// ModuleForTarget returns the module to use for a target if the scrape does
+// not request one. It is concurrency-safe.
+func (sc *SafeConfig) ModuleForTarget(target string) string {
+	sc.Lock()
+	defer sc.Unlock()
+	return sc.C.moduleForTarget(target)
+}
+
 // ConfigForTarget returns the config for a given target/module, or the
-// default. It is concurrency-safe.
+// default, with the credentials of the matching target rule applied. It is
+// concurrency-safe.
 func (sc *SafeConfig) ConfigForTarget(target, module string) IPMIConfig {
 	sc.Lock()
 	defer sc.Unlock()
@@ -317,5 +1210,8 @@ func (sc *SafeConfig) ConfigForTarget(target, module string) IPMIConfig {
 		}
 	}
 
+	if r := sc.C.targetRule(target); r != nil {
+		config = r.apply(config)
+	}
 	return config
 }
@@ -0,0 +1,184 @@
+// Copyright 2025 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/prometheus/common/promslog"
+	"go.yaml.in/yaml/v2"
+)
+
+func init() {
+	logger = promslog.NewNopLogger()
+}
+
+func parseConfig(t *testing.T, config string) Config {
+	t.Helper()
+	var c Config
+	if err := yaml.Unmarshal([]byte(config), &c); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestExtendsKeepsRepeatedArgs(t *testing.T) {
+	c := parseConfig(t, `
+modules:
+  base:
+    custom_args:
+      ipmi: ["-W", "discretereading"]
+  child:
+    extends: base
+    custom_args:
+      ipmi: ["-W", "assumeio"]
+`)
+	want := []string{"-W", "discretereading", "-W", "assumeio"}
+	if got := c.Modules["child"].CustomArgs[IPMICollectorName]; !slices.Equal(got, want) {
+		t.Errorf("custom_args: got %q, want %q", got, want)
+	}
+}
+
+func TestExtendsDedupsCollectors(t *testing.T) {
+	c := parseConfig(t, `
+modules:
+  base:
+    collectors: [bmc, ipmi]
+    exclude_sensor_ids: [1, 2]
+  child:
+    extends: base
+    collectors: [ipmi, sel]
+    exclude_sensor_ids: [2, 3]
+`)
+	m := c.Modules["child"]
+	wantCollectors := []CollectorName{BMCCollectorName, IPMICollectorName, SELCollectorName}
+	if !slices.Equal(m.Collectors, wantCollectors) {
+		t.Errorf("collectors: got %q, want %q", m.Collectors, wantCollectors)
+	}
+	wantIDs := []int64{1, 2, 3}
+	if !slices.Equal(m.ExcludeSensorIDs, wantIDs) {
+		t.Errorf("exclude_sensor_ids: got %v, want %v", m.ExcludeSensorIDs, wantIDs)
+	}
+}
+
+func TestExtendsInheritsDefaultCollectors(t *testing.T) {
+	c := parseConfig(t, `
+modules:
+  base:
+    user: monitoring
+  child:
+    extends: base
+    collectors: [sel]
+`)
+	want := append(slices.Clone(defaultConfig.Collectors), SELCollectorName)
+	if got := c.Modules["child"].Collectors; !slices.Equal(got, want) {
+		t.Errorf("collectors: got %q, want %q", got, want)
+	}
+}
+
+func TestPasswordIsLiteral(t *testing.T) {
+	t.Setenv("BMC_PW", "from-env")
+	c := parseConfig(t, `
+modules:
+  literal:
+    pass: "pa${BMC_PW}ss"
+  env:
+    pass_env: BMC_PW
+`)
+	if got := c.Modules["literal"].Password; got != "pa${BMC_PW}ss" {
+		t.Errorf("pass: got %q", got)
+	}
+	if got := c.Modules["env"].Password; got != "from-env" {
+		t.Errorf("pass_env: got %q", got)
+	}
+}
+
+func TestPasswordFileExpandsEnv(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "pw$1"), []byte("secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SECRETS_DIR", dir)
+	c := parseConfig(t, `
+modules:
+  file:
+    pass_file: "${SECRETS_DIR}/pw$$1"
+`)
+	if got := c.Modules["file"].Password; got != "secret" {
+		t.Errorf("pass_file: got %q", got)
+	}
+}
+
+func TestTargetRuleMatchesWithPort(t *testing.T) {
+	c := parseConfig(t, `
+targets:
+  - match: 10.1.3.0/24
+  - match: "bmc-*.example.com"
+  - match: bmc.example.org
+  - match: "2001:db8::/32"
+`)
+	for _, tc := range []struct {
+		target string
+		rule   int
+	}{
+		{"10.1.3.5", 0},
+		{"10.1.3.5:623", 0},
+		{"bmc-1.example.com:623", 1},
+		{"bmc.example.org:623", 2},
+		{"BMC.example.org", 2},
+		{"[2001:db8::1]:623", 3},
+		{"10.1.4.5:623", -1},
+	} {
+		got := slices.IndexFunc(c.Targets, func(r TargetRule) bool { return r.Matches(tc.target) })
+		if got != tc.rule {
+			t.Errorf("%s: matched rule %d, want %d", tc.target, got, tc.rule)
+		}
+	}
+}
+
+func TestCollectorOptionsUnsupported(t *testing.T) {
+	for _, module := range []string{
+		"{collector_options: {ipmi: {shared_sensors: true}}, default_args: {ipmi: [-Q]}}",
+		"{collector_options: {sel-events: {max_entries: 10}}, default_args: {sel-events: [-Q]}}",
+	} {
+		var c Config
+		if err := yaml.Unmarshal([]byte("modules: {m: "+module+"}"), &c); err == nil {
+			t.Errorf("%s: expected error", module)
+		}
+	}
+	parseConfig(t, "modules: {m: {backend: native, collector_options: {sel-events: {max_entries: 10}}, default_args: {sel-events: [-Q]}}}")
+	parseConfig(t, "modules: {m: {backend: native, collector_options: {ipmi: {bridge_sensors: true}}, default_args: {ipmi: [-Q]}}}")
+}
+
+func TestExampleConfigsLoadWithBothBackends(t *testing.T) {
+	files, err := filepath.Glob("ipmi_*.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no example configs found")
+	}
+	defer func(native bool) { *nativeIPMI = native }(*nativeIPMI)
+	for _, native := range []bool{false, true} {
+		*nativeIPMI = native
+		for _, f := range files {
+			if _, err := LoadConfig(f); err != nil {
+				t.Errorf("%s (native: %t): %s", f, native, err)
+			}
+		}
+	}
+}
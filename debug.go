@@ -0,0 +1,224 @@
+// Copyright 2025 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+
+	"github.com/prometheus-community/ipmi_exporter/freeipmi"
+)
+
+// collectorTrace records what the backend of a single collector returned.
+// All methods are no-ops on a nil trace.
+type collectorTrace struct {
+	mu        sync.Mutex
+	result    *freeipmi.Result
+	responses []nativeResponse
+}
+
+// nativeResponse is a decoded response to a native IPMI request.
+type nativeResponse struct {
+	Request  string          `json:"request"`
+	Response json.RawMessage `json:"response,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// freeipmi records the result of running a FreeIPMI tool.
+func (t *collectorTrace) freeipmi(result freeipmi.Result) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.result = &result
+}
+
+// native records the response to a native IPMI request.
+func (t *collectorTrace) native(request string, response any, err error) {
+	if t == nil {
+		return
+	}
+	r := nativeResponse{Request: request}
+	if err != nil {
+		r.Error = err.Error()
+	} else {
+		b, jsonErr := json.Marshal(response)
+		if jsonErr != nil {
+			// Some responses contain values JSON cannot represent, e.g. NaN.
+			b, _ = json.Marshal(fmt.Sprintf("%+v", response))
+		}
+		r.Response = b
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.responses = append(t.responses, r)
+}
+
+// debugCollector is the outcome of a single collector as shown by the debug
+// endpoint.
+type debugCollector struct {
+	Name            string           `json:"name"`
+	Backend         string           `json:"backend"`
+	Command         []string         `json:"command,omitempty"`
+	FreeipmiConfig  string           `json:"freeipmi_config,omitempty"`
+	ExitCode        *int             `json:"exit_code,omitempty"`
+	Stdout          string           `json:"stdout,omitempty"`
+	Stderr          string           `json:"stderr,omitempty"`
+	NativeResponses []nativeResponse `json:"native_responses,omitempty"`
+	Start           time.Time        `json:"start"`
+	DurationSeconds float64          `json:"duration_seconds"`
+	Error           string           `json:"error,omitempty"`
+	Reason          string           `json:"reason,omitempty"`
+	Metrics         string           `json:"metrics"`
+}
+
+// secretArgs are FreeIPMI options whose value is a secret.
+var secretArgs = []string{"-p", "--password", "-k", "--k-g"}
+
+// redactArgs returns a copy of a command line with the values of all options
+// taking secrets redacted.
+func redactArgs(args []string) []string {
+	redacted := slices.Clone(args)
+	for i, arg := range args {
+		if slices.Contains(secretArgs, arg) && i+1 < len(args) {
+			redacted[i+1] = redact(args[i+1])
+		} else if name, value, ok := strings.Cut(arg, "="); ok && slices.Contains(secretArgs, name) {
+			redacted[i] = name + "=" + redact(value)
+		}
+	}
+	return redacted
+}
+
+// debugHandler runs the collectors of a module against a target, one after
+// the other, and returns what each of them executed, the raw output of the
+// backend and the metrics produced. Without a target, the local host is
+// scraped.
+func debugHandler(w http.ResponseWriter, r *http.Request) {
+	target := r.URL.Query().Get("target")
+	module := r.URL.Query().Get("module")
+	if module == "" {
+		module = sc.ModuleForTarget(target)
+	}
+	if module != "default" && !sc.HasModule(module) {
+		http.Error(w, fmt.Sprintf("Unknown module %q", module), http.StatusBadRequest)
+		return
+	}
+	config := sc.ConfigForTarget(target, module)
+	filter, err := parseCollectorFilter(r.URL.Query(), config)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel, err := scrapeContext(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer cancel()
+	if target != targetLocal {
+		release, err := limiter.acquire(ctx, target)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Scrape rejected: %s", err), http.StatusServiceUnavailable)
+			return
+		}
+		defer release()
+	}
+
+	logger.Info("Running collectors for debug endpoint", "target", targetName(target), "module", module)
+	mc := metaCollector{ctx: ctx, target: target, module: module, config: sc, filter: filter}
+	session := newNativeSession(target, module, config)
+	defer session.close(ctx)
+
+	var result struct {
+		Target     string           `json:"target"`
+		Module     string           `json:"module"`
+		Collectors []debugCollector `json:"collectors"`
+	}
+	result.Target = target
+	result.Module = module
+	for _, collector := range filter.apply(config.GetCollectors()) {
+		trace := &collectorTrace{}
+		t := ipmiTarget{host: target, config: config, native: session, trace: trace}
+		d := debugCollector{
+			Name:    string(collector.Name()),
+			Backend: backendFreeipmi,
+			Start:   time.Now(),
+		}
+
+		var err error
+		metrics := collectMetrics(prometheus.CollectorFunc(func(ch chan<- prometheus.Metric) {
+			err = mc.runCollector(collector, ch, t)
+		}))
+
+		d.DurationSeconds = time.Since(d.Start).Seconds()
+		if err != nil {
+			d.Error = err.Error()
+			d.Reason = classifyError(err)
+		}
+		if collector.Cmd() == "" {
+			d.Backend = backendNative
+		}
+		if res := trace.result; res != nil {
+			d.Command = redactArgs(res.Command())
+			redacted := config.Redacted()
+			d.FreeipmiConfig = redacted.GetFreeipmiConfig()
+			exitCode := res.ExitCode()
+			d.ExitCode = &exitCode
+			d.Stdout = string(res.Stdout())
+			d.Stderr = string(res.Stderr())
+		}
+		d.NativeResponses = trace.responses
+		d.Metrics, err = formatMetrics(metrics)
+		if err != nil {
+			d.Metrics = fmt.Sprintf("error formatting metrics: %s", err)
+		}
+		result.Collectors = append(result.Collectors, d)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(result); err != nil {
+		logger.Error("Error writing debug output", "error", err)
+	}
+}
+
+// formatMetrics renders metrics in the Prometheus text format.
+func formatMetrics(metrics []prometheus.Metric) (string, error) {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(metricsCollector(metrics))
+	families, err := registry.Gather()
+	if err != nil {
+		return "", err
+	}
+	var b bytes.Buffer
+	for _, mf := range families {
+		if _, err := expfmt.MetricFamilyToText(&b, mf); err != nil {
+			return "", err
+		}
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2025 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// scrapeGroup deduplicates concurrent scrapes. While a scrape for a given key
+// is running, further scrapes for the same key wait for it and share its
+// result instead of doing all the IPMI work again.
+type scrapeGroup struct {
+	mu    sync.Mutex
+	calls map[string]*scrapeCall
+}
+
+type scrapeCall struct {
+	done    chan struct{}
+	metrics []prometheus.Metric
+	err     error
+}
+
+func newScrapeGroup() *scrapeGroup {
+	return &scrapeGroup{calls: map[string]*scrapeCall{}}
+}
+
+// do runs fn, unless a call for the same key is already running, in which
+// case it waits for that call and returns its result. shared is true if the
+// result was (or may be) returned to more than one caller.
+//
+// fn must not depend on the cancellation of the first caller's context: the
+// other callers are still waiting for the result when the first one gives up.
+// Waiting is aborted once ctx is done.
+func (g *scrapeGroup) do(ctx context.Context, key string, fn func() ([]prometheus.Metric, error)) ([]prometheus.Metric, bool, error) {
+	g.mu.Lock()
+	if call, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+		select {
+		case <-call.done:
+			return call.metrics, true, call.err
+		case <-ctx.Done():
+			return nil, true, ctx.Err()
+		}
+	}
+	call := &scrapeCall{done: make(chan struct{})}
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	call.metrics, call.err = fn()
+
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+	close(call.done)
+	return call.metrics, false, call.err
+}
+
+// detachContext returns a context that keeps the deadline of ctx, but is not
+// cancelled when ctx is. This is used for scrapes shared between requests, so
+// that one client going away does not abort the scrape for the others. It is
+// still cancelled when scrapes are aborted on shutdown.
+func detachContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	detached := context.WithoutCancel(ctx)
+	var cancel context.CancelFunc
+	if deadline, ok := ctx.Deadline(); ok {
+		detached, cancel = context.WithDeadline(detached, deadline)
+	} else {
+		detached, cancel = context.WithCancel(detached)
+	}
+	stop := context.AfterFunc(serverCtx, cancel)
+	return detached, func() {
+		stop()
+		cancel()
+	}
+}
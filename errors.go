@@ -0,0 +1,109 @@
+// Copyright 2025 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"net"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/bougou/go-ipmi"
+)
+
+// Reasons reported in the ipmi_collector_error metric.
+const (
+	errorReasonAuth              = "auth_failure"
+	errorReasonTimeout           = "timeout"
+	errorReasonConnectionRefused = "connection_refused"
+	errorReasonPrivilege         = "insufficient_privilege"
+	errorReasonParse             = "parse_error"
+	errorReasonCommandNotFound   = "command_not_found"
+	errorReasonCircuitOpen       = "circuit_open"
+	errorReasonUnknown           = "unknown"
+)
+
+// Error messages as printed by the FreeIPMI tools or returned by go-ipmi,
+// mapped to the reason they indicate. Errors from FreeIPMI only reach us as
+// text. The order matters, as some messages contain others.
+var errorReasonPatterns = []struct {
+	pattern string
+	reason  string
+}{
+	// FreeIPMI
+	{"privilege level insufficient", errorReasonPrivilege},
+	{"privilege level cannot be obtained", errorReasonPrivilege},
+	{"username invalid", errorReasonAuth},
+	{"password invalid", errorReasonAuth},
+	{"k_g invalid", errorReasonAuth},
+	{"password verification timeout", errorReasonAuth},
+	{"authentication type unavailable", errorReasonAuth},
+	{"connection timeout", errorReasonTimeout},
+	{"session timeout", errorReasonTimeout},
+	{"executable file not found", errorReasonCommandNotFound},
+	{"no such file or directory", errorReasonCommandNotFound},
+	{"could not find value in output", errorReasonParse},
+	{"unexpected raw response", errorReasonParse},
+	// go-ipmi
+	{"unauthorized role of privilege level", errorReasonPrivilege},
+	{"insufficient privilege level", errorReasonPrivilege},
+	{"unauthorized name", errorReasonAuth},
+	{"invalid integrity check value", errorReasonAuth},
+	{"rakp", errorReasonAuth},
+	// both
+	{"connection refused", errorReasonConnectionRefused},
+	{"i/o timeout", errorReasonTimeout},
+	{"deadline exceeded", errorReasonTimeout},
+	{"unexpected number of octets", errorReasonParse},
+	{"unexpected lan mode status", errorReasonParse},
+}
+
+// classifyError sorts an error returned by a collector into one of a few
+// broad reasons, so that users can tell why a collector failed without
+// looking at the logs.
+func classifyError(err error) string {
+	var (
+		respErr  *ipmi.ResponseError
+		netErr   net.Error
+		numErr   *strconv.NumError
+		csvErr   *csv.ParseError
+		errorMsg = strings.ToLower(err.Error())
+	)
+	switch {
+	case errors.Is(err, errCircuitOpen):
+		return errorReasonCircuitOpen
+	case errors.Is(err, context.DeadlineExceeded):
+		return errorReasonTimeout
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return errorReasonConnectionRefused
+	case errors.Is(err, exec.ErrNotFound):
+		return errorReasonCommandNotFound
+	case errors.As(err, &respErr) && respErr.CompletionCode() == ipmi.CompletionCodeCannotExecuteCommandSecurityRestrict:
+		return errorReasonPrivilege
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return errorReasonTimeout
+	case errors.As(err, &numErr), errors.As(err, &csvErr), errors.Is(err, ipmi.ErrUnpackedDataTooShort):
+		return errorReasonParse
+	}
+	for _, p := range errorReasonPatterns {
+		if strings.Contains(errorMsg, p.pattern) {
+			return p.reason
+		}
+	}
+	return errorReasonUnknown
+}
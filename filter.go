@@ -0,0 +1,95 @@
+// Copyright 2025 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// collectorFilter restricts a scrape to a subset of the collectors of a
+// module, as requested via the collect[] and exclude[] URL parameters.
+type collectorFilter struct {
+	include []CollectorName
+	exclude []CollectorName
+}
+
+// parseCollectorFilter reads the collect[] and exclude[] parameters of a
+// request. Collectors to include must be enabled in the given config.
+func parseCollectorFilter(params url.Values, config IPMIConfig) (collectorFilter, error) {
+	var f collectorFilter
+	for _, name := range params["collect[]"] {
+		c := CollectorName(name)
+		if err := c.IsValid(); err != nil {
+			return f, err
+		}
+		if !slices.Contains(config.Collectors, c) {
+			return f, fmt.Errorf("collector %q is not enabled in module", name)
+		}
+		f.include = append(f.include, c)
+	}
+	for _, name := range params["exclude[]"] {
+		c := CollectorName(name)
+		if err := c.IsValid(); err != nil {
+			return f, err
+		}
+		f.exclude = append(f.exclude, c)
+	}
+	return f, nil
+}
+
+// empty returns true if the filter lets all collectors through.
+func (f collectorFilter) empty() bool {
+	return len(f.include) == 0 && len(f.exclude) == 0
+}
+
+// key returns a string identifying the set of collectors selected by the
+// filter, for use in cache keys. It is empty if the filter is.
+func (f collectorFilter) key() string {
+	if f.empty() {
+		return ""
+	}
+	include := make([]string, len(f.include))
+	for i, c := range f.include {
+		include[i] = string(c)
+	}
+	exclude := make([]string, len(f.exclude))
+	for i, c := range f.exclude {
+		exclude[i] = string(c)
+	}
+	slices.Sort(include)
+	slices.Sort(exclude)
+	return "\x00" + strings.Join(include, ",") + "\x00" + strings.Join(exclude, ",")
+}
+
+// apply returns the collectors that pass the filter.
+func (f collectorFilter) apply(collectors []collector) []collector {
+	if f.empty() {
+		return collectors
+	}
+	var result []collector
+	for _, c := range collectors {
+		name := c.Name()
+		if len(f.include) > 0 && !slices.Contains(f.include, name) {
+			continue
+		}
+		if slices.Contains(f.exclude, name) {
+			continue
+		}
+		result = append(result, c)
+	}
+	return result
+}
@@ -16,10 +16,14 @@ package freeipmi
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"crypto/rand"
 	"encoding/csv"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"log/slog"
 	"math"
 	"os"
@@ -29,6 +33,7 @@ import (
 	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 )
 
@@ -55,11 +60,69 @@ var (
 	bmcWatchdogCurrentCountdownRegex    = regexp.MustCompile(`^Current Countdown:\s*(?P<value>[0-9.]*)\s*seconds.*`)
 )
 
+// running keeps track of the FreeIPMI processes currently running and the
+// named pipes their configs are passed through, so that they can be cleaned up
+// on shutdown. Each pipe maps to a channel that is closed once the config was
+// written to it.
+var running = struct {
+	sync.Mutex
+	procs map[*os.Process]struct{}
+	pipes map[string]chan struct{}
+}{
+	procs: map[*os.Process]struct{}{},
+	pipes: map[string]chan struct{}{},
+}
+
 // Result represents the outcome of a call to one of the FreeIPMI tools.
 // It can be used with other functions in this package to extract data.
 type Result struct {
 	output []byte
 	err    error
+
+	// Details of the execution, for debugging.
+	command  []string
+	stdout   []byte
+	stderr   []byte
+	exitCode int
+}
+
+// Command returns the command line the tool was run with.
+func (r Result) Command() []string {
+	return r.command
+}
+
+// Stdout returns what the tool wrote to its standard output.
+func (r Result) Stdout() []byte {
+	return r.stdout
+}
+
+// Stderr returns what the tool wrote to its standard error.
+func (r Result) Stderr() []byte {
+	return r.stderr
+}
+
+// ExitCode returns the exit code of the tool, or -1 if it did not exit
+// normally or was not run at all.
+func (r Result) ExitCode() int {
+	return r.exitCode
+}
+
+// Err returns the error running the tool failed with, if any.
+func (r Result) Err() error {
+	return r.err
+}
+
+// lockedWriter serializes writes to an underlying writer, so that it can be
+// shared by the stdout and stderr of a command.
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
 }
 
 // SensorData represents the reading of a single sensor.
@@ -129,30 +192,39 @@ func freeipmiConfigPipe(config string, logger *slog.Logger) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	written := make(chan struct{})
+	running.Lock()
+	running.pipes[pipe] = written
+	running.Unlock()
 
 	go func(file string, data []byte) {
-		f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_APPEND, os.ModeNamedPipe)
+		defer close(written)
+		// Opening blocks until the pipe is opened for reading, either by the
+		// tool or by removePipe. The pipe must not be created here: if it was
+		// removed in the meantime, the config would end up in a regular file.
+		f, err := os.OpenFile(file, os.O_WRONLY, 0)
 		if err != nil {
-			logger.Error("Error opening pipe", "error", err)
+			if !errors.Is(err, fs.ErrNotExist) {
+				logger.Error("Error opening pipe", "error", err)
+			}
+			return
 		}
+		defer f.Close()
 		if _, err := f.Write(data); err != nil {
 			logger.Error("Error writing config to pipe", "error", err)
 		}
-		f.Close()
 	}(pipe, content)
 	return pipe, nil
 }
 
-func Execute(cmd string, args []string, config string, target string, logger *slog.Logger) Result {
+// Execute runs a FreeIPMI tool with the given config. The process is killed if
+// ctx is done before it exits.
+func Execute(ctx context.Context, cmd string, args []string, config string, target string, logger *slog.Logger) Result {
 	pipe, err := freeipmiConfigPipe(config, logger)
 	if err != nil {
-		return Result{nil, err}
+		return Result{err: err, exitCode: -1}
 	}
-	defer func() {
-		if err := os.Remove(pipe); err != nil {
-			logger.Error("Error deleting named pipe", "error", err)
-		}
-	}()
+	defer removePipe(pipe, logger)
 
 	args = append(args, "--config-file", pipe)
 	if target != "" {
@@ -160,11 +232,100 @@ func Execute(cmd string, args []string, config string, target string, logger *sl
 	}
 
 	logger.Debug("Executing", "command", cmd, "args", fmt.Sprintf("%+v", args))
-	out, err := exec.Command(cmd, args...).CombinedOutput()
+	var combined, stdout, stderr bytes.Buffer
+	w := &lockedWriter{w: &combined}
+	c := exec.CommandContext(ctx, cmd, args...)
+	c.Stdout = io.MultiWriter(w, &stdout)
+	c.Stderr = io.MultiWriter(w, &stderr)
+	// Run the tool in its own process group, so that it can be killed along
+	// with any children running as the same user. Processes running as
+	// another user, e.g. if the tool is wrapped in sudo, can only be killed
+	// if the exporter itself runs as root.
+	c.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	c.Cancel = func() error {
+		return killProcessGroup(c.Process)
+	}
+	err = c.Start()
+	if err == nil {
+		running.Lock()
+		running.procs[c.Process] = struct{}{}
+		running.Unlock()
+		err = c.Wait()
+		running.Lock()
+		delete(running.procs, c.Process)
+		running.Unlock()
+	}
 	if err != nil {
-		err = fmt.Errorf("error running %s: %s", cmd, err)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			err = fmt.Errorf("error running %s: %w", cmd, ctxErr)
+		} else {
+			err = fmt.Errorf("error running %s: %s", cmd, err)
+		}
+	}
+	return Result{
+		output:   combined.Bytes(),
+		err:      err,
+		command:  append([]string{cmd}, args...),
+		stdout:   stdout.Bytes(),
+		stderr:   stderr.Bytes(),
+		exitCode: c.ProcessState.ExitCode(),
+	}
+}
+
+func killProcessGroup(p *os.Process) error {
+	return syscall.Kill(-p.Pid, syscall.SIGKILL)
+}
+
+func removePipe(pipe string, logger *slog.Logger) {
+	running.Lock()
+	written, ok := running.pipes[pipe]
+	delete(running.pipes, pipe)
+	running.Unlock()
+	if ok {
+		releasePipe(pipe, written)
+	}
+	if err := os.Remove(pipe); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		logger.Error("Error deleting named pipe", "error", err)
+	}
+}
+
+// releasePipe waits for the config to be written to a pipe. If the tool never
+// opened the pipe, e.g. because it failed to start, the pipe is opened for
+// reading here to unblock the writer. The config fits into the pipe buffer, so
+// the write completes without anything being read.
+func releasePipe(pipe string, written <-chan struct{}) {
+	r, err := os.OpenFile(pipe, os.O_RDONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return
+	}
+	defer r.Close()
+	<-written
+}
+
+// Cleanup kills the process groups of all FreeIPMI tools that are still
+// running and removes the named pipes created for them. It is meant to be
+// called on shutdown, after all scrapes were given the chance to finish.
+func Cleanup(logger *slog.Logger) {
+	running.Lock()
+	defer running.Unlock()
+	for p := range running.procs {
+		logger.Warn("Killing FreeIPMI process", "pid", p.Pid)
+		err := killProcessGroup(p)
+		switch {
+		case err == nil, errors.Is(err, syscall.ESRCH):
+		case errors.Is(err, syscall.EPERM):
+			logger.Error("Not permitted to kill FreeIPMI process, it may be running as another user", "pid", p.Pid, "error", err)
+		default:
+			logger.Error("Error killing FreeIPMI process", "pid", p.Pid, "error", err)
+		}
+	}
+	for pipe, written := range running.pipes {
+		releasePipe(pipe, written)
+		if err := os.Remove(pipe); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			logger.Error("Error deleting named pipe", "error", err)
+		}
+		delete(running.pipes, pipe)
 	}
-	return Result{out, err}
 }
 
 func GetSensorData(ipmiOutput Result, excludeSensorIDs []int64) ([]SensorData, error) {
@@ -0,0 +1,69 @@
+// Copyright 2025 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package freeipmi
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestExecuteLeavesNoConfigBehind(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	logger := slog.New(slog.DiscardHandler)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for range 10 {
+		if res := Execute(ctx, "true", nil, "password secret\n", "", logger); res.Err() == nil {
+			t.Fatal("expected error executing with canceled context")
+		}
+	}
+	if res := Execute(context.Background(), "/nonexistent/ipmimonitoring", nil, "password secret\n", "", logger); res.Err() == nil {
+		t.Fatal("expected error executing nonexistent command")
+	}
+
+	// Execute must not return before the writer of the config is done. Give
+	// writers that are left running the chance to leave a file behind.
+	time.Sleep(100 * time.Millisecond)
+	entries, err := os.ReadDir(tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		t.Errorf("file left behind: %s", e.Name())
+	}
+	running.Lock()
+	defer running.Unlock()
+	if len(running.pipes) != 0 {
+		t.Errorf("%d pipes still tracked", len(running.pipes))
+	}
+}
+
+func TestExecutePassesConfig(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	logger := slog.New(slog.DiscardHandler)
+
+	// Without a target, the config file is the last argument.
+	res := Execute(context.Background(), "sh", []string{"-c", `cat "$2"`, "sh"}, "password secret\n", "", logger)
+	if err := res.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(res.Stdout()); got != "password secret\n" {
+		t.Errorf("unexpected config read from pipe: %q", got)
+	}
+}
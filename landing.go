@@ -0,0 +1,103 @@
+// Copyright 2025 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"html/template"
+	"net/http"
+	"slices"
+	"time"
+)
+
+var landingTemplate = template.Must(template.New("landing").Funcs(template.FuncMap{
+	"targetName": targetName,
+	"round": func(d time.Duration) time.Duration {
+		return d.Round(time.Millisecond)
+	},
+}).Parse(`<html>
+<head>
+<title>IPMI Exporter</title>
+<style>
+body { font-family: sans-serif; }
+form label { display: inline-block; width: 75px; margin: 10px; }
+form input, form select { margin: 10px; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+.ok { color: #080; }
+.failed { color: #c00; }
+</style>
+</head>
+<body>
+<h1>IPMI Exporter</h1>
+<form action="/ipmi">
+<label for="target">Target:</label> <input type="text" id="target" name="target" placeholder="X.X.X.X"><br>
+<label for="module">Module:</label> <select id="module" name="module">
+<option value="">(default or from target rules)</option>
+{{- range .Modules}}
+<option value="{{.}}">{{.}}</option>
+{{- end}}
+</select><br>
+<input type="submit" value="Submit">
+</form>
+<p><a href="/metrics">Local metrics</a></p>
+<p><a href="/config">Config</a></p>
+<h2>Recent scrapes</h2>
+{{- if .Scrapes}}
+<table>
+<tr><th>Time</th><th>Target</th><th>Module</th><th>Duration</th><th>Collectors</th><th>Last error</th></tr>
+{{- range .Scrapes}}
+<tr>
+<td>{{.Time.Format "2006-01-02 15:04:05"}}</td>
+<td>{{if .Target}}<a href="/ipmi?target={{.Target}}&amp;module={{.Module}}">{{.Target}}</a>{{else}}<a href="/metrics">{{targetName .Target}}</a>{{end}}</td>
+<td><a href="/config?target={{.Target}}&amp;module={{.Module}}">{{.Module}}</a></td>
+<td>{{round .Duration}}</td>
+<td>
+{{- range .Collectors}}
+{{- if .Err}}
+<span class="failed" title="{{.Err}}">{{.Name}}: failed ({{.Reason}}), {{round .Duration}}</span><br>
+{{- else}}
+<span class="ok">{{.Name}}: ok, {{round .Duration}}</span><br>
+{{- end}}
+{{- end}}
+</td>
+<td>{{if .LastError}}{{.LastErrorTime.Format "2006-01-02 15:04:05"}}: {{.LastError}}{{end}}</td>
+</tr>
+{{- end}}
+</table>
+{{- else}}
+<p>No scrapes yet.</p>
+{{- end}}
+</body>
+</html>
+`))
+
+// landingHandler serves the landing page, with a form to scrape a target and
+// the status of recent scrapes.
+func landingHandler(w http.ResponseWriter, _ *http.Request) {
+	modules := sc.ModuleNames()
+	if !slices.Contains(modules, "default") {
+		modules = append([]string{"default"}, modules...)
+	}
+	data := struct {
+		Modules []string
+		Scrapes []scrapeStatus
+	}{
+		Modules: modules,
+		Scrapes: scrapeHistory.recent(),
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := landingTemplate.Execute(w, data); err != nil {
+		logger.Error("Error rendering landing page", "error", err)
+	}
+}
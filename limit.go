@@ -0,0 +1,164 @@
+// Copyright 2025 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	errQueueTimeout = errors.New("timed out waiting for a free scrape slot")
+
+	scrapeQueueDepth = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "exporter",
+		Name:      "scrape_queue_depth",
+		Help:      "Number of scrapes currently waiting for a free slot.",
+	})
+	scrapeQueueWait = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "exporter",
+		Name:      "scrape_queue_wait_seconds",
+		Help:      "Time scrapes spent waiting for a free slot.",
+		Buckets:   []float64{.01, .05, .1, .5, 1, 2.5, 5, 10, 30, 60},
+	})
+	scrapeQueueTimeouts = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "exporter",
+		Name:      "scrape_queue_timeouts_total",
+		Help:      "Number of scrapes that were rejected because no slot became free in time.",
+	})
+)
+
+// scrapeLimiter limits the number of scrapes running at the same time, both
+// overall and per target.
+type scrapeLimiter struct {
+	global    chan struct{}
+	perTarget int
+	timeout   time.Duration
+
+	mu      sync.Mutex
+	targets map[string]*targetSlots
+}
+
+type targetSlots struct {
+	sem  chan struct{}
+	refs int
+}
+
+// newScrapeLimiter returns a limiter allowing maxGlobal scrapes overall and
+// maxPerTarget scrapes per target at the same time. A limit of 0 means no
+// limit. Scrapes wait for at most timeout for a free slot.
+func newScrapeLimiter(maxGlobal, maxPerTarget int, timeout time.Duration) *scrapeLimiter {
+	l := &scrapeLimiter{
+		perTarget: maxPerTarget,
+		timeout:   timeout,
+		targets:   map[string]*targetSlots{},
+	}
+	if maxGlobal > 0 {
+		l.global = make(chan struct{}, maxGlobal)
+	}
+	return l
+}
+
+// acquire waits for a free slot for the given target. The returned function
+// must be called to free the slot again once the scrape is done.
+func (l *scrapeLimiter) acquire(ctx context.Context, target string) (func(), error) {
+	start := time.Now()
+	scrapeQueueDepth.Inc()
+	defer func() {
+		scrapeQueueDepth.Dec()
+		scrapeQueueWait.Observe(time.Since(start).Seconds())
+	}()
+
+	if l.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, l.timeout)
+		defer cancel()
+	}
+
+	// Wait for the target first, so that scrapes queued behind a slow target
+	// do not block slots other targets could use.
+	var slots *targetSlots
+	if l.perTarget > 0 {
+		slots = l.targetSlots(target)
+		select {
+		case slots.sem <- struct{}{}:
+		case <-ctx.Done():
+			l.releaseTarget(target, slots, false)
+			return nil, l.waitError(ctx)
+		}
+	}
+	if l.global != nil {
+		select {
+		case l.global <- struct{}{}:
+		case <-ctx.Done():
+			if slots != nil {
+				l.releaseTarget(target, slots, true)
+			}
+			return nil, l.waitError(ctx)
+		}
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			if l.global != nil {
+				<-l.global
+			}
+			if slots != nil {
+				l.releaseTarget(target, slots, true)
+			}
+		})
+	}, nil
+}
+
+func (l *scrapeLimiter) waitError(ctx context.Context) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		scrapeQueueTimeouts.Inc()
+		return errQueueTimeout
+	}
+	return ctx.Err()
+}
+
+func (l *scrapeLimiter) targetSlots(target string) *targetSlots {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	slots, ok := l.targets[target]
+	if !ok {
+		slots = &targetSlots{sem: make(chan struct{}, l.perTarget)}
+		l.targets[target] = slots
+	}
+	slots.refs++
+	return slots
+}
+
+// releaseTarget drops a reference to the slots of a target, freeing a slot
+// first if one was held. Targets without references are forgotten.
+func (l *scrapeLimiter) releaseTarget(target string, slots *targetSlots, held bool) {
+	if held {
+		<-slots.sem
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	slots.refs--
+	if slots.refs == 0 {
+		delete(l.targets, target)
+	}
+}
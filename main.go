@@ -14,12 +14,17 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
+	"time"
 
 	kingpin "github.com/alecthomas/kingpin/v2"
 	"github.com/prometheus/client_golang/prometheus"
@@ -30,13 +35,18 @@ import (
 	"github.com/prometheus/common/version"
 	"github.com/prometheus/exporter-toolkit/web"
 	webflag "github.com/prometheus/exporter-toolkit/web/kingpinflag"
+	"go.yaml.in/yaml/v2"
 )
 
 var (
 	configFile = kingpin.Flag(
 		"config.file",
-		"Path to configuration file.",
+		"Path to configuration file, or to a directory or glob pattern of configuration files to merge.",
 	).String()
+	configCheck = kingpin.Flag(
+		"config.check",
+		"Validate the configuration file, print the effective configuration with secrets redacted, and exit.",
+	).Bool()
 	executablesPath = kingpin.Flag(
 		"freeipmi.path",
 		"Path to FreeIPMI executables (default: rely on $PATH).",
@@ -45,6 +55,62 @@ var (
 		"native-ipmi",
 		"Use native IPMI implementation instead of FreeIPMI (EXPERIMENTAL)",
 	).Bool()
+	nativeSessionPool = kingpin.Flag(
+		"native-ipmi.session-pool",
+		"Keep native IPMI sessions to remote targets open between scrapes.",
+	).Bool()
+	nativeSessionKeepalive = kingpin.Flag(
+		"native-ipmi.session-pool.keepalive-interval",
+		"Interval in which keepalives are sent for idle pooled sessions.",
+	).Default("30s").Duration()
+	nativeSessionIdleTimeout = kingpin.Flag(
+		"native-ipmi.session-pool.idle-timeout",
+		"Close pooled sessions that were not used for this long.",
+	).Default("5m").Duration()
+	maxConcurrentScrapes = kingpin.Flag(
+		"scrape.max-concurrent",
+		"Maximum number of remote scrapes running at the same time (0: no limit).",
+	).Default("0").Int()
+	maxConcurrentScrapesPerTarget = kingpin.Flag(
+		"scrape.max-concurrent-per-target",
+		"Maximum number of scrapes of a single target running at the same time (0: no limit).",
+	).Default("1").Int()
+	scrapeQueueTimeout = kingpin.Flag(
+		"scrape.queue-timeout",
+		"Maximum time a scrape waits for a free slot before it is rejected.",
+	).Default("10s").Duration()
+	breakerThreshold = kingpin.Flag(
+		"breaker.failure-threshold",
+		"Number of consecutive scrapes failing to reach a target after which further scrapes fail fast (0: disabled).",
+	).Default("3").Int()
+	breakerBackoff = kingpin.Flag(
+		"breaker.backoff",
+		"Time for which scrapes of an unreachable target fail fast, doubled after every failed retry.",
+	).Default("30s").Duration()
+	breakerMaxBackoff = kingpin.Flag(
+		"breaker.max-backoff",
+		"Maximum time for which scrapes of an unreachable target fail fast.",
+	).Default("10m").Duration()
+	configReloadInterval = kingpin.Flag(
+		"config.auto-reload-interval",
+		"Check the configuration file for changes in this interval and reload it if necessary (0: disabled).",
+	).Default("0s").Duration()
+	timeoutOffset = kingpin.Flag(
+		"scrape.timeout-offset",
+		"Offset to subtract from the timeout requested by Prometheus, in seconds.",
+	).Default("0.5").Float64()
+	debugEndpoint = kingpin.Flag(
+		"web.enable-debug-endpoint",
+		"Enable the /debug/ipmi endpoint, which runs collectors and returns their raw output. It reveals details of the targets, so only enable it where needed.",
+	).Bool()
+	recentScrapes = kingpin.Flag(
+		"web.recent-scrapes",
+		"Number of targets whose latest scrape is shown on the landing page (0: disabled).",
+	).Default("100").Int()
+	shutdownGracePeriod = kingpin.Flag(
+		"web.shutdown-grace-period",
+		"Time to wait for in-flight scrapes on shutdown before aborting them.",
+	).Default("30s").Duration()
 	webConfig = webflag.AddFlags(kingpin.CommandLine, ":9290")
 
 	sc = &SafeConfig{
@@ -52,6 +118,16 @@ var (
 	}
 	reloadCh chan chan error
 
+	// nativePool is nil unless the native session pool is enabled.
+	nativePool *sessionPool
+	pollers    = newPoller()
+	limiter    *scrapeLimiter
+	scrapes    = newScrapeGroup()
+	// breaker is nil if the circuit breaker is disabled.
+	breaker *circuitBreaker
+	// scrapeHistory holds the status of recent scrapes for the landing page.
+	scrapeHistory *scrapeLog
+
 	logger *slog.Logger
 )
 
@@ -65,22 +141,138 @@ func remoteIPMIHandler(w http.ResponseWriter, r *http.Request) {
 	// Remote scrape will not work without some kind of config, so be pedantic about it
 	module := r.URL.Query().Get("module")
 	if module == "" {
-		module = "default"
+		module = sc.ModuleForTarget(target)
 	}
 	if !sc.HasModule(module) {
 		http.Error(w, fmt.Sprintf("Unknown module %q", module), http.StatusBadRequest)
 		return
 	}
 
-	logger.Debug("Scraping target", "target", target, "module", module)
+	filter, err := parseCollectorFilter(r.URL.Query(), sc.ConfigForTarget(target, module))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel, err := scrapeContext(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer cancel()
 
 	registry := prometheus.NewRegistry()
-	remoteCollector := metaCollector{target: target, module: module, config: sc}
-	registry.MustRegister(remoteCollector)
+	// Polled results always contain all collectors of the module.
+	if result, ok := pollers.result(target, module); ok && filter.empty() {
+		logger.Debug("Serving polled result", "target", target, "module", module)
+		registry.MustRegister(result)
+	} else {
+		metrics, shared, err := scrapes.do(ctx, targetKey(target, module)+filter.key(), func() ([]prometheus.Metric, error) {
+			ctx, cancel := detachContext(ctx)
+			defer cancel()
+
+			release, err := limiter.acquire(ctx, target)
+			if err != nil {
+				return nil, err
+			}
+			defer release()
+
+			logger.Debug("Scraping target", "target", target, "module", module)
+			return collectMetrics(metaCollector{ctx: ctx, target: target, module: module, config: sc, filter: filter}), nil
+		})
+		if err != nil {
+			logger.Warn("Rejecting scrape", "target", target, "module", module, "error", err)
+			http.Error(w, fmt.Sprintf("Scrape rejected: %s", err), http.StatusServiceUnavailable)
+			return
+		}
+		if shared {
+			logger.Debug("Shared result of concurrent scrape", "target", target, "module", module)
+		}
+		registry.MustRegister(metricsCollector(metrics))
+	}
 	h := promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
 	h.ServeHTTP(w, r)
 }
 
+func localIPMIHandler(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseCollectorFilter(r.URL.Query(), sc.ConfigForTarget(targetLocal, "default"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(metaCollector{ctx: r.Context(), target: targetLocal, module: "default", config: sc, filter: filter})
+	gatherers := prometheus.Gatherers{prometheus.DefaultGatherer, registry}
+	h := promhttp.HandlerFor(gatherers, promhttp.HandlerOpts{})
+	h.ServeHTTP(w, r)
+}
+
+// configHandler shows the loaded config, or the effective config of a single
+// module, with all secrets redacted.
+func configHandler(w http.ResponseWriter, r *http.Request) {
+	var v any = sc.Redacted()
+	module := r.URL.Query().Get("module")
+	target := r.URL.Query().Get("target")
+	if module != "" || target != "" {
+		if module == "" {
+			module = sc.ModuleForTarget(target)
+		}
+		if module != "default" && !sc.HasModule(module) {
+			http.Error(w, fmt.Sprintf("Unknown module %q", module), http.StatusNotFound)
+			return
+		}
+		v = sc.ConfigForTarget(target, module).Redacted()
+	}
+	out, err := yaml.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = w.Write(out)
+}
+
+// scrapeContext returns a context for a scrape request. If Prometheus sent its
+// scrape timeout, the context expires shortly before Prometheus gives up.
+func scrapeContext(r *http.Request) (context.Context, context.CancelFunc, error) {
+	header := r.Header.Get("X-Prometheus-Scrape-Timeout-Seconds")
+	if header == "" {
+		ctx, cancel := context.WithCancel(r.Context())
+		return ctx, cancel, nil
+	}
+	timeoutSeconds, err := strconv.ParseFloat(header, 64)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse timeout from Prometheus header: %s", err)
+	}
+	if *timeoutOffset >= timeoutSeconds {
+		// Offset would leave no time at all, ignore it.
+		logger.Warn("Timeout offset should be lower than prometheus scrape timeout", "offset", *timeoutOffset, "timeout", timeoutSeconds)
+	} else {
+		timeoutSeconds -= *timeoutOffset
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), time.Duration(timeoutSeconds*float64(time.Second)))
+	return ctx, cancel, nil
+}
+
+// checkConfig validates a config file and prints the effective config with
+// all secrets redacted. It returns the exit code.
+func checkConfig(configFile string) int {
+	c, err := LoadConfig(configFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "FAILED: %s\n", err)
+		return 1
+	}
+	out, err := yaml.Marshal(c.Redacted())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "FAILED: %s\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "SUCCESS: %s is valid\n", configFile)
+	_, _ = os.Stdout.Write(out)
+	return 0
+}
+
 func updateConfiguration(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case "POST":
@@ -104,30 +296,69 @@ func main() {
 	kingpin.Version(version.Print("ipmi_exporter"))
 	kingpin.Parse()
 	logger = promslog.New(promslogConfig)
+	if *configCheck {
+		os.Exit(checkConfig(*configFile))
+	}
 	logger.Info("Starting ipmi_exporter", "version", version.Info())
 	if *nativeIPMI {
 		logger.Info("Using Go-native IPMI implementation - this is currently EXPERIMENTAL")
 		logger.Info("Make sure to read https://github.com/prometheus-community/ipmi_exporter/blob/master/docs/native.md")
 	}
+	if *nativeSessionPool {
+		nativePool = newSessionPool(*nativeSessionKeepalive, *nativeSessionIdleTimeout)
+		prometheus.MustRegister(nativePool, nativeSessionsReused, nativeSessionsFailed)
+	}
+
+	limiter = newScrapeLimiter(*maxConcurrentScrapes, *maxConcurrentScrapesPerTarget, *scrapeQueueTimeout)
+	prometheus.MustRegister(scrapeQueueDepth, scrapeQueueWait, scrapeQueueTimeouts)
+	if *breakerThreshold > 0 {
+		breaker = newCircuitBreaker(*breakerThreshold, *breakerBackoff, *breakerMaxBackoff)
+	}
+	scrapeHistory = newScrapeLog(*recentScrapes)
+
+	prometheus.MustRegister(configReloadSuccess, configReloadSeconds, configInfo)
 
 	// Bail early if the config is bad.
-	if err := sc.ReloadConfig(*configFile); err != nil {
+	if err := reloadConfig(); err != nil {
 		logger.Error("Error parsing config file", "error", err)
 		os.Exit(1)
 	}
 
+	watcher := &configWatcher{hash: sc.Hash()}
+	var watchTick <-chan time.Time
+	if *configReloadInterval > 0 && *configFile != "" {
+		watchTick = time.NewTicker(*configReloadInterval).C
+	}
+
 	hup := make(chan os.Signal, 1)
 	reloadCh = make(chan chan error)
 	signal.Notify(hup, syscall.SIGHUP)
 	go func() {
+		// reload reloads the config and makes the watcher aware of what was
+		// loaded, so that it does not load the same config again.
+		reload := func() error {
+			if err := reloadConfig(); err != nil {
+				return err
+			}
+			watcher.hash = sc.Hash()
+			return nil
+		}
 		for {
 			select {
+			case <-watchTick:
+				if !watcher.changed(*configFile) {
+					continue
+				}
+				logger.Info("Config file changed, reloading", "path", *configFile)
+				if err := reload(); err != nil {
+					logger.Error("Error reloading config", "error", err)
+				}
 			case <-hup:
-				if err := sc.ReloadConfig(*configFile); err != nil {
+				if err := reload(); err != nil {
 					logger.Error("Error reloading config", "error", err)
 				}
 			case rc := <-reloadCh:
-				if err := sc.ReloadConfig(*configFile); err != nil {
+				if err := reload(); err != nil {
 					logger.Error("Error reloading config", "error", err)
 					rc <- err
 				} else {
@@ -138,45 +369,40 @@ func main() {
 	}()
 
 	prometheus.MustRegister(versioncollector.NewCollector("ipmi_exporter"))
-	localCollector := metaCollector{target: targetLocal, module: "default", config: sc}
-	prometheus.MustRegister(&localCollector)
 
-	http.Handle("/metrics", promhttp.Handler())       // Regular metrics endpoint for local IPMI metrics.
+	// Regular metrics endpoint for local IPMI metrics.
+	http.Handle("/metrics", promhttp.InstrumentMetricHandler(prometheus.DefaultRegisterer, http.HandlerFunc(localIPMIHandler)))
 	http.HandleFunc("/ipmi", remoteIPMIHandler)       // Endpoint to do IPMI scrapes.
 	http.HandleFunc("/-/reload", updateConfiguration) // Endpoint to reload configuration.
+	http.HandleFunc("/config", configHandler)         // Endpoint to show the loaded configuration.
+	http.HandleFunc("/sd", sdHandler)                 // Endpoint for HTTP service discovery.
+	// JSON API for structured data of remote targets.
+	http.Handle("/api/v1/sensors", apiSensorsEndpoint)
+	http.Handle("/api/v1/sel", apiSELEndpoint)
+	http.Handle("/api/v1/bmc", apiBMCEndpoint)
+
+	if *debugEndpoint {
+		http.HandleFunc("/debug/ipmi", debugHandler) // Endpoint to debug collectors.
+	}
+	http.HandleFunc("/", landingHandler)
+
+	srv := &http.Server{
+		BaseContext: func(net.Listener) context.Context { return serverCtx },
+	}
+	term := make(chan os.Signal, 1)
+	signal.Notify(term, os.Interrupt, syscall.SIGTERM)
+	stopped := make(chan struct{})
+	go func() {
+		sig := <-term
+		logger.Info("Shutting down", "signal", sig, "grace_period", *shutdownGracePeriod)
+		shutdown(srv, *shutdownGracePeriod)
+		close(stopped)
+	}()
 
-	http.HandleFunc("/", func(w http.ResponseWriter, _ *http.Request) {
-		_, _ = w.Write([]byte(`<html>
-            <head>
-            <title>IPMI Exporter</title>
-            <style>
-            label{
-            display:inline-block;
-            width:75px;
-            }
-            form label {
-            margin: 10px;
-            }
-            form input {
-            margin: 10px;
-            }
-            </style>
-            </head>
-            <body>
-            <h1>IPMI Exporter</h1>
-            <form action="/ipmi">
-            <label>Target:</label> <input type="text" name="target" placeholder="X.X.X.X" value="1.2.3.4"><br>
-            <input type="submit" value="Submit">
-			</form>
-			<p><a href="/metrics">Local metrics</a></p>
-			<p><a href="/config">Config</a></p>
-            </body>
-            </html>`))
-	})
-
-	srv := &http.Server{}
-	if err := web.ListenAndServe(srv, webConfig, logger); err != nil {
+	if err := web.ListenAndServe(srv, webConfig, logger); !errors.Is(err, http.ErrServerClosed) {
 		logger.Error("HTTP listener stopped", "error", err)
 		os.Exit(1)
 	}
+	<-stopped
+	logger.Info("Shutdown complete")
 }
@@ -0,0 +1,285 @@
+// Copyright 2025 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/bougou/go-ipmi"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	nativeSessionsOpenDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "exporter", "native_sessions_open"),
+		"Number of native IPMI sessions currently held open by the session pool.",
+		nil,
+		nil,
+	)
+	nativeSessionsReused = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "exporter",
+		Name:      "native_sessions_reused_total",
+		Help:      "Number of scrapes that reused a pooled native IPMI session.",
+	})
+	nativeSessionsFailed = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "exporter",
+		Name:      "native_session_failures_total",
+		Help:      "Number of pooled native IPMI sessions that could not be opened or were found dead.",
+	})
+)
+
+// nativeConn is an open native IPMI connection, along with the information
+// needed to decide whether it can be reused.
+type nativeConn struct {
+	client    *ipmi.Client
+	privilege ipmi.PrivilegeLevel
+	config    IPMIConfig
+	verified  time.Time
+}
+
+func openNativeConn(ctx context.Context, target ipmiTarget) (*nativeConn, error) {
+	client, err := NewNativeClient(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	return &nativeConn{
+		client:    client,
+		privilege: client.SessionPrivilegeLevel(),
+		config:    target.config,
+		verified:  time.Now(),
+	}, nil
+}
+
+// matches returns true if the connection was opened with the same settings
+// as the given config, i.e. it is still valid after a config reload.
+func (c *nativeConn) matches(config IPMIConfig) bool {
+	return c.config.User == config.User &&
+		c.config.Password == config.Password &&
+		c.config.Privilege == config.Privilege &&
+		c.config.Timeout == config.Timeout
+}
+
+// ping checks whether the session is still alive on the BMC.
+func (c *nativeConn) ping(ctx context.Context) error {
+	if _, err := c.client.GetCurrentSessionInfo(ctx); err != nil {
+		return err
+	}
+	c.verified = time.Now()
+	return nil
+}
+
+// sessionPool keeps native IPMI sessions to remote targets open between
+// scrapes, saving the RMCP+ handshake on every scrape. Sessions are keyed by
+// target and module. Idle sessions are kept alive, and closed once they were
+// not used for a while.
+type sessionPool struct {
+	keepaliveInterval time.Duration
+	idleTimeout       time.Duration
+
+	mu   sync.Mutex
+	idle map[string]*pooledConn
+	open int
+	stop chan struct{}
+	once sync.Once
+	wg   sync.WaitGroup
+}
+
+type pooledConn struct {
+	*nativeConn
+	lastUsed time.Time
+}
+
+func newSessionPool(keepaliveInterval, idleTimeout time.Duration) *sessionPool {
+	p := &sessionPool{
+		keepaliveInterval: keepaliveInterval,
+		idleTimeout:       idleTimeout,
+		idle:              map[string]*pooledConn{},
+		stop:              make(chan struct{}),
+	}
+	p.wg.Add(1)
+	go p.run()
+	return p
+}
+
+// Describe implements prometheus.Collector.
+func (p *sessionPool) Describe(ch chan<- *prometheus.Desc) {
+	ch <- nativeSessionsOpenDesc
+}
+
+// Collect implements prometheus.Collector.
+func (p *sessionPool) Collect(ch chan<- prometheus.Metric) {
+	p.mu.Lock()
+	open := p.open
+	p.mu.Unlock()
+	ch <- prometheus.MustNewConstMetric(nativeSessionsOpenDesc, prometheus.GaugeValue, float64(open))
+}
+
+// get returns an open connection for the given key, reusing an idle pooled
+// one if possible. Sessions that were not verified recently are checked
+// before being handed out, so that a BMC reset results in a reconnect rather
+// than a failed scrape.
+func (p *sessionPool) get(ctx context.Context, key string, target ipmiTarget) (*nativeConn, error) {
+	p.mu.Lock()
+	pc, ok := p.idle[key]
+	if ok {
+		delete(p.idle, key)
+	}
+	p.mu.Unlock()
+
+	if ok {
+		if !pc.matches(target.config) {
+			logger.Debug("Pooled session outdated, reconnecting", "target", targetName(target.host))
+			p.discard(ctx, pc.nativeConn)
+		} else if time.Since(pc.verified) < p.keepaliveInterval {
+			nativeSessionsReused.Inc()
+			return pc.nativeConn, nil
+		} else if err := pc.ping(ctx); err != nil {
+			logger.Debug("Pooled session is dead, reconnecting", "target", targetName(target.host), "error", err)
+			nativeSessionsFailed.Inc()
+			p.discard(ctx, pc.nativeConn)
+		} else {
+			nativeSessionsReused.Inc()
+			return pc.nativeConn, nil
+		}
+	}
+
+	conn, err := openNativeConn(ctx, target)
+	if err != nil {
+		nativeSessionsFailed.Inc()
+		return nil, err
+	}
+	p.mu.Lock()
+	p.open++
+	p.mu.Unlock()
+	return conn, nil
+}
+
+// put returns a connection to the pool. Unhealthy connections, and
+// connections for which another idle one is already pooled, are closed.
+func (p *sessionPool) put(ctx context.Context, key string, conn *nativeConn, healthy bool) {
+	if healthy {
+		p.mu.Lock()
+		_, taken := p.idle[key]
+		if !taken && !p.closed() {
+			p.idle[key] = &pooledConn{nativeConn: conn, lastUsed: time.Now()}
+			p.mu.Unlock()
+			return
+		}
+		p.mu.Unlock()
+	}
+	p.discard(ctx, conn)
+}
+
+func (p *sessionPool) discard(ctx context.Context, conn *nativeConn) {
+	CloseNativeClient(ctx, conn.client)
+	p.mu.Lock()
+	p.open--
+	p.mu.Unlock()
+}
+
+func (p *sessionPool) closed() bool {
+	select {
+	case <-p.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// run sends keepalives for idle sessions and evicts those that were idle for
+// too long or no longer respond.
+func (p *sessionPool) run() {
+	defer p.wg.Done()
+	ticker := time.NewTicker(p.keepaliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.maintain()
+		}
+	}
+}
+
+// maintain checks the idle sessions one at a time, so that the others remain
+// available to scrapes meanwhile and a large pool does not flood the network
+// with keepalives.
+func (p *sessionPool) maintain() {
+	p.mu.Lock()
+	idle := maps.Clone(p.idle)
+	p.mu.Unlock()
+
+	for key, pc := range idle {
+		select {
+		case <-p.stop:
+			return
+		default:
+		}
+		p.mu.Lock()
+		if p.idle[key] != pc {
+			// Taken by a scrape since.
+			p.mu.Unlock()
+			continue
+		}
+		delete(p.idle, key)
+		p.mu.Unlock()
+		p.maintainConn(key, pc)
+	}
+}
+
+func (p *sessionPool) maintainConn(key string, pc *pooledConn) {
+	ctx, cancel := context.WithTimeout(context.Background(), p.keepaliveInterval)
+	defer cancel()
+
+	if time.Since(pc.lastUsed) > p.idleTimeout {
+		logger.Debug("Closing idle pooled session", "target", pc.client.Host)
+		p.discard(ctx, pc.nativeConn)
+		return
+	}
+	if err := pc.ping(ctx); err != nil {
+		logger.Debug("Pooled session keepalive failed", "target", pc.client.Host, "error", err)
+		nativeSessionsFailed.Inc()
+		p.discard(ctx, pc.nativeConn)
+		return
+	}
+	p.mu.Lock()
+	if _, taken := p.idle[key]; taken || p.closed() {
+		p.mu.Unlock()
+		p.discard(ctx, pc.nativeConn)
+		return
+	}
+	p.idle[key] = pc
+	p.mu.Unlock()
+}
+
+// shutdown stops the keepalive loop and closes all idle sessions. Sessions
+// currently in use are closed once they are returned.
+func (p *sessionPool) shutdown(ctx context.Context) {
+	p.once.Do(func() { close(p.stop) })
+	p.wg.Wait()
+
+	p.mu.Lock()
+	idle := p.idle
+	p.idle = map[string]*pooledConn{}
+	p.mu.Unlock()
+	for _, pc := range idle {
+		p.discard(ctx, pc.nativeConn)
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2025 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/bougou/go-ipmi"
+)
+
+// nativeSession is a native IPMI session shared by all collectors of a single
+// scrape. It is only opened once the first native collector needs it, so
+// scrapes running FreeIPMI collectors only never connect to the BMC.
+//
+// If the session pool is enabled, the underlying connection is taken from
+// and returned to the pool instead of being opened and closed.
+type nativeSession struct {
+	target ipmiTarget
+	module string
+	pool   *sessionPool
+
+	mu      sync.Mutex
+	conn    *nativeConn
+	err     error
+	healthy bool
+}
+
+func newNativeSession(host, module string, config IPMIConfig) *nativeSession {
+	s := &nativeSession{
+		target:  ipmiTarget{host: host, config: config},
+		module:  module,
+		healthy: true,
+	}
+	// Local sessions are cheap, only pool remote ones.
+	if host != targetLocal {
+		s.pool = nativePool
+	}
+	return s
+}
+
+// acquire returns the client of the session, connecting first if necessary.
+// go-ipmi clients must not be used concurrently, so the session stays locked
+// until release is called. On error, the session is not locked.
+//
+// If connecting fails, all further calls return the same error, so that an
+// unreachable BMC does not cost one connection timeout per collector.
+func (s *nativeSession) acquire(ctx context.Context) (*ipmi.Client, error) {
+	s.mu.Lock()
+	if s.conn == nil && s.err == nil {
+		if s.pool != nil {
+			s.conn, s.err = s.pool.get(ctx, targetKey(s.target.host, s.module), s.target)
+		} else {
+			s.conn, s.err = openNativeConn(ctx, s.target)
+		}
+	}
+	if s.err != nil {
+		s.mu.Unlock()
+		return nil, s.err
+	}
+	return s.conn.client, nil
+}
+
+// release unlocks a session previously locked by acquire.
+func (s *nativeSession) release() {
+	s.mu.Unlock()
+}
+
+// raisePrivilege makes sure the session runs with at least the given privilege
+// level. The session is kept open, so collectors running later benefit from
+// the raised level as well. Must only be called between acquire and release.
+func (s *nativeSession) raisePrivilege(ctx context.Context, level ipmi.PrivilegeLevel) error {
+	if s.conn.privilege != ipmi.PrivilegeLevelUnspecified && s.conn.privilege >= level {
+		return nil
+	}
+	if _, err := s.conn.client.SetSessionPrivilegeLevel(ctx, level); err != nil {
+		return fmt.Errorf("failed to set privilege level to %s: %w", level, err)
+	}
+	s.conn.privilege = level
+	return nil
+}
+
+// reportError records a collector error. Unless the BMC answered with an
+// error completion code, the session is considered broken and will not be
+// reused.
+func (s *nativeSession) reportError(err error) {
+	var respErr *ipmi.ResponseError
+	if errors.As(err, &respErr) {
+		return
+	}
+	s.mu.Lock()
+	s.healthy = false
+	s.mu.Unlock()
+}
+
+// close closes the session (or returns it to the pool) if it was ever opened.
+func (s *nativeSession) close(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return
+	}
+	if s.pool != nil {
+		s.pool.put(ctx, targetKey(s.target.host, s.module), s.conn, s.healthy)
+	} else {
+		CloseNativeClient(ctx, s.conn.client)
+	}
+	s.conn = nil
+}
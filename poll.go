@@ -0,0 +1,190 @@
+// Copyright 2025 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	lastPollDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "last_poll", "timestamp_seconds"),
+		"Unix timestamp of the completion of the last background poll of the target.",
+		nil,
+		nil,
+	)
+
+	pollStaleDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "poll", "stale"),
+		"'1' if the last background poll is older than twice the poll interval, '0' otherwise.",
+		nil,
+		nil,
+	)
+)
+
+// pollResult is the outcome of a single background poll.
+type pollResult struct {
+	metrics  []prometheus.Metric
+	time     time.Time
+	interval time.Duration
+}
+
+// Describe implements prometheus.Collector.
+func (r *pollResult) Describe(_ chan<- *prometheus.Desc) {
+	// all metrics are described ad-hoc
+}
+
+// Collect implements prometheus.Collector.
+func (r *pollResult) Collect(ch chan<- prometheus.Metric) {
+	for _, m := range r.metrics {
+		ch <- m
+	}
+	stale := 0.0
+	if time.Since(r.time) > 2*r.interval {
+		stale = 1
+	}
+	ch <- prometheus.MustNewConstMetric(lastPollDesc, prometheus.GaugeValue, float64(r.time.UnixNano())/1e9)
+	ch <- prometheus.MustNewConstMetric(pollStaleDesc, prometheus.GaugeValue, stale)
+}
+
+// poller polls the targets listed in the config in the background and keeps
+// the latest result of each.
+type poller struct {
+	mu      sync.RWMutex
+	results map[string]*pollResult
+	running map[string]*pollLoop
+}
+
+type pollLoop struct {
+	target PollTarget
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func newPoller() *poller {
+	return &poller{
+		results: map[string]*pollResult{},
+		running: map[string]*pollLoop{},
+	}
+}
+
+// result returns the latest poll result for a target/module, if there is one.
+func (p *poller) result(target, module string) (*pollResult, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	r, ok := p.results[targetKey(target, module)]
+	return r, ok
+}
+
+// update starts and stops polling loops so that they match the given list of
+// targets. Loops for targets whose settings did not change keep running.
+func (p *poller) update(targets []PollTarget) {
+	wanted := map[string]PollTarget{}
+	for _, t := range targets {
+		wanted[targetKey(t.Target, t.Module)] = t
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for key, loop := range p.running {
+		// Labels do not matter for polling.
+		if t, ok := wanted[key]; ok && t.Interval == loop.target.Interval && t.Jitter == loop.target.Jitter {
+			continue
+		}
+		loop.cancel()
+		delete(p.running, key)
+		delete(p.results, key)
+	}
+	for key, t := range wanted {
+		if _, ok := p.running[key]; ok {
+			continue
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		loop := &pollLoop{target: t, cancel: cancel, done: make(chan struct{})}
+		p.running[key] = loop
+		go p.run(ctx, key, loop)
+	}
+}
+
+// stop stops all polling loops and waits for them to return.
+func (p *poller) stop() {
+	p.mu.Lock()
+	loops := p.running
+	p.running = map[string]*pollLoop{}
+	p.mu.Unlock()
+	for _, loop := range loops {
+		loop.cancel()
+		<-loop.done
+	}
+}
+
+func (p *poller) run(ctx context.Context, key string, loop *pollLoop) {
+	defer close(loop.done)
+	interval := time.Duration(loop.target.Interval)
+	jitter := time.Duration(loop.target.Jitter)
+
+	// Spread the first polls of all targets over the jitter interval.
+	wait := randDuration(jitter)
+	logger.Debug("Starting background polling", "target", loop.target.Target, "module", loop.target.Module, "interval", interval)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+		p.poll(ctx, key, loop)
+		wait = interval - jitter + randDuration(2*jitter)
+	}
+}
+
+func (p *poller) poll(ctx context.Context, key string, loop *pollLoop) {
+	target := loop.target
+	interval := time.Duration(target.Interval)
+	ctx, cancel := context.WithTimeout(ctx, interval)
+	defer cancel()
+
+	release, err := limiter.acquire(ctx, target.Target)
+	if err != nil {
+		logger.Warn("Skipping poll", "target", target.Target, "module", target.Module, "error", err)
+		return
+	}
+	defer release()
+
+	logger.Debug("Polling target", "target", target.Target, "module", target.Module)
+	metrics := collectMetrics(metaCollector{ctx: ctx, target: target.Target, module: target.Module, config: sc})
+	if errors.Is(ctx.Err(), context.Canceled) {
+		// Polling loop was stopped while the poll was running.
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.running[key] != loop {
+		return
+	}
+	p.results[key] = &pollResult{metrics: metrics, time: time.Now(), interval: interval}
+}
+
+func randDuration(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return rand.N(d)
+}
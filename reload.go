@@ -0,0 +1,87 @@
+// Copyright 2025 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	configReloadSuccess = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "exporter",
+		Name:      "config_last_reload_successful",
+		Help:      "Whether the last configuration reload attempt was successful.",
+	})
+	configReloadSeconds = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "exporter",
+		Name:      "config_last_reload_success_timestamp_seconds",
+		Help:      "Timestamp of the last successful configuration reload.",
+	})
+	configInfo = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "exporter",
+		Name:      "config_info",
+		Help:      "A metric with a constant '1' value labeled by the SHA-256 hash of the loaded configuration.",
+	}, []string{"hash"})
+)
+
+// reloadConfig reloads the config file and applies it to everything that
+// depends on it.
+func reloadConfig() error {
+	if err := sc.ReloadConfig(*configFile); err != nil {
+		configReloadSuccess.Set(0)
+		return err
+	}
+	configReloadSuccess.Set(1)
+	configReloadSeconds.SetToCurrentTime()
+	configInfo.Reset()
+	configInfo.WithLabelValues(sc.Hash()).Set(1)
+	pollers.update(sc.PollTargets())
+	return nil
+}
+
+// configWatcher detects changes of the config files by comparing the hash of
+// their content, so that replacing the file, e.g. by swapping symlinks as done
+// for Kubernetes ConfigMaps, is noticed as well.
+type configWatcher struct {
+	// hash is the hash of the content last attempted to load, so that a
+	// broken config is not reloaded over and over again.
+	hash string
+}
+
+// changed returns true if the content of the config file differs from the
+// one seen last time.
+func (w *configWatcher) changed(configFile string) bool {
+	fragments, err := readConfigFiles(configFile)
+	if err != nil {
+		logger.Warn("Failed to read config file", "path", configFile, "error", err)
+		return false
+	}
+	hash := hashConfigFiles(fragments)
+	if hash == w.hash {
+		return false
+	}
+	w.hash = hash
+	return true
+}
+
+func configHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,77 @@
+// Copyright 2025 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"maps"
+	"net/http"
+)
+
+// sdTargetGroup is a group of targets in the format of the Prometheus HTTP
+// service discovery.
+type sdTargetGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels,omitempty"`
+}
+
+// discoveryTargets returns all targets known from the config, i.e. the poll
+// targets and the targets of rules matching a single host, each with the
+// labels of the target rule applying to it and its module. The module is
+// also set as the module URL parameter of the scrape.
+func (s *Config) discoveryTargets() []sdTargetGroup {
+	groups := []sdTargetGroup{}
+	seen := map[string]bool{}
+	add := func(target, module string, labels map[string]string) {
+		key := targetKey(target, module)
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		l := map[string]string{}
+		if r := s.targetRule(target); r != nil {
+			maps.Copy(l, r.Labels)
+		}
+		maps.Copy(l, labels)
+		l["module"] = module
+		l["__param_module"] = module
+		groups = append(groups, sdTargetGroup{Targets: []string{target}, Labels: l})
+	}
+	for _, t := range s.PollTargets {
+		add(t.Target, t.Module, t.Labels)
+	}
+	for _, r := range s.Targets {
+		if r.exact() {
+			add(r.Match, s.moduleForTarget(r.Match), nil)
+		}
+	}
+	return groups
+}
+
+// DiscoveryTargets returns the targets to be served for service discovery. It
+// is concurrency-safe.
+func (sc *SafeConfig) DiscoveryTargets() []sdTargetGroup {
+	sc.Lock()
+	defer sc.Unlock()
+	return sc.C.discoveryTargets()
+}
+
+// sdHandler serves the targets known from the config for the Prometheus HTTP
+// service discovery.
+func sdHandler(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(sc.DiscoveryTargets()); err != nil {
+		logger.Error("Error writing service discovery targets", "error", err)
+	}
+}
@@ -0,0 +1,192 @@
+// Copyright 2025 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"maps"
+	"slices"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// sensorMetric names one of the metric families sensors are exported as.
+type sensorMetric string
+
+const (
+	sensorMetricGeneric       sensorMetric = "generic"
+	sensorMetricTemperature   sensorMetric = "temperature"
+	sensorMetricFanSpeedRPM   sensorMetric = "fan_speed_rpm"
+	sensorMetricFanSpeedRatio sensorMetric = "fan_speed_ratio"
+	sensorMetricVoltage       sensorMetric = "voltage"
+	sensorMetricCurrent       sensorMetric = "current"
+	sensorMetricPower         sensorMetric = "power"
+	sensorMetricAirflow       sensorMetric = "airflow"
+)
+
+// Legends of the sensor state metrics. The native collector knows more states
+// than FreeIPMI reports.
+const (
+	sensorStatesFreeipmi = "0=nominal, 1=warning, 2=critical"
+	sensorStatesNative   = "0=nominal, 1=warning, 2=critical, 3=non-recoverable"
+)
+
+// sensorMetricSpecs describes the metric families sensors are exported as.
+var sensorMetricSpecs = map[sensorMetric]struct {
+	subsystem, name, help, stateHelp string
+	scale                            float64
+}{
+	sensorMetricGeneric:       {"sensor", "value", "Generic data read from an IPMI sensor of unknown type, relying on labels for context.", "Indicates the severity of the state reported by an IPMI sensor", 1},
+	sensorMetricTemperature:   {"temperature", "celsius", "Temperature reading in degree Celsius.", "Reported state of a temperature sensor", 1},
+	sensorMetricFanSpeedRPM:   {"fan_speed", "rpm", "Fan speed in rotations per minute.", "Reported state of a fan speed sensor", 1},
+	sensorMetricFanSpeedRatio: {"fan_speed", "ratio", "Fan speed as a proportion of the maximum speed.", "Reported state of a fan speed sensor", 0.01},
+	sensorMetricVoltage:       {"voltage", "volts", "Voltage reading in Volts.", "Reported state of a voltage sensor", 1},
+	sensorMetricCurrent:       {"current", "amperes", "Current reading in Amperes.", "Reported state of a current sensor", 1},
+	sensorMetricPower:         {"power", "watts", "Power reading in Watts.", "Reported state of a power sensor", 1},
+	sensorMetricAirflow:       {"airflow", "cfm", "Airflow reading in cubic feet per minute.", "Reported state of an airflow sensor", 1},
+}
+
+// sensorFamily holds the descriptors of one sensor metric family.
+type sensorFamily struct {
+	value, state *prometheus.Desc
+	scale        float64
+	// generic families carry the sensor type as a label.
+	generic bool
+}
+
+// sensorFamilies are the sensor metric families, with the given label names
+// in addition to the standard ones.
+type sensorFamilies map[sensorMetric]sensorFamily
+
+func newSensorFamilies(states string, extraLabels []string) sensorFamilies {
+	families := sensorFamilies{}
+	for metric, spec := range sensorMetricSpecs {
+		generic := metric == sensorMetricGeneric
+		labels := []string{"id", "name"}
+		if generic {
+			labels = append(labels, "type")
+		}
+		labels = append(labels, extraLabels...)
+		families[metric] = sensorFamily{
+			value: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, spec.subsystem, spec.name),
+				spec.help,
+				labels,
+				nil,
+			),
+			state: prometheus.NewDesc(
+				prometheus.BuildFQName(namespace, spec.subsystem, "state"),
+				spec.stateHelp+" ("+states+").",
+				labels,
+				nil,
+			),
+			scale:   spec.scale,
+			generic: generic,
+		}
+	}
+	return families
+}
+
+// describe sends the descriptors of all families.
+func (f sensorFamilies) describe(ch chan<- *prometheus.Desc) {
+	for _, metric := range slices.Sorted(maps.Keys(f)) {
+		ch <- f[metric].value
+		ch <- f[metric].state
+	}
+}
+
+// sensorReading is a single sensor reading, independent of the backend it was
+// read with.
+type sensorReading struct {
+	id    int64
+	name  string
+	typ   string
+	unit  string
+	value float64
+	state float64
+}
+
+// collect sends the value and state of a sensor reading, as the given metric
+// unless the sensor rules say otherwise.
+func (f sensorFamilies) collect(ch chan<- prometheus.Metric, rules []SensorRule, labelNames []string, r sensorReading, metric sensorMetric) {
+	name, metric, labels, keep := applySensorRules(rules, r, metric)
+	if !keep {
+		return
+	}
+	family := f[metric]
+	labelValues := []string{strconv.FormatInt(r.id, 10), name}
+	if family.generic {
+		labelValues = append(labelValues, r.typ)
+	}
+	for _, l := range labelNames {
+		labelValues = append(labelValues, labels[l])
+	}
+	ch <- prometheus.MustNewConstMetric(
+		family.value,
+		prometheus.GaugeValue,
+		r.value*family.scale,
+		labelValues...,
+	)
+	ch <- prometheus.MustNewConstMetric(
+		family.state,
+		prometheus.GaugeValue,
+		r.state,
+		labelValues...,
+	)
+}
+
+// applySensorRules applies all matching rules to a sensor reading, in order.
+// It returns the name and metric to export the sensor with and its additional
+// labels, or false if the sensor is dropped.
+func applySensorRules(rules []SensorRule, r sensorReading, metric sensorMetric) (string, sensorMetric, map[string]string, bool) {
+	name := r.name
+	var labels map[string]string
+	for _, rule := range rules {
+		if !rule.matches(name, r.typ, r.unit) {
+			continue
+		}
+		if rule.Drop {
+			return "", "", nil, false
+		}
+		if rule.Rename != "" {
+			name = rule.rename(name)
+		}
+		if rule.Metric != "" {
+			metric = sensorMetric(rule.Metric)
+		}
+		for k, v := range rule.Labels {
+			if labels == nil {
+				labels = map[string]string{}
+			}
+			labels[k] = v
+		}
+	}
+	return name, metric, labels, true
+}
+
+// sensorLabelNames returns the names of all labels added by sensor rules. All
+// sensor metrics of a scrape carry all of them, so that the label names are
+// the same within each metric family.
+func sensorLabelNames(rules []SensorRule) []string {
+	var names []string
+	for _, rule := range rules {
+		for k := range rule.Labels {
+			if !slices.Contains(names, k) {
+				names = append(names, k)
+			}
+		}
+	}
+	slices.Sort(names)
+	return names
+}
@@ -0,0 +1,58 @@
+// Copyright 2025 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/prometheus-community/ipmi_exporter/freeipmi"
+)
+
+// serverCtx is the base context of all requests and scrapes. It is cancelled
+// on shutdown once the grace period for in-flight scrapes has expired.
+var serverCtx, abortScrapes = context.WithCancel(context.Background())
+
+// shutdown stops the exporter gracefully: it stops accepting requests and
+// waits up to gracePeriod for in-flight requests to complete. Scrapes still
+// running after that are aborted. Finally, leftover FreeIPMI processes are
+// killed and pooled native sessions are closed.
+func shutdown(srv *http.Server, gracePeriod time.Duration) {
+	// Background polls are not waited for, nobody is waiting for their
+	// results.
+	pollers.stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), gracePeriod)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		logger.Warn("Grace period expired, aborting running scrapes", "error", err)
+		abortScrapes()
+		// Aborted scrapes still close their native sessions, which may
+		// take a moment.
+		ctx, cancel := context.WithTimeout(context.Background(), 2*nativeCloseTimeout)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			logger.Warn("Scrapes did not finish after being aborted", "error", err)
+		}
+	}
+	abortScrapes()
+	freeipmi.Cleanup(logger)
+
+	if nativePool != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), nativeCloseTimeout)
+		defer cancel()
+		nativePool.shutdown(ctx)
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2025 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"time"
+)
+
+// scrapeStatus is the outcome of the latest scrape of a target/module.
+type scrapeStatus struct {
+	Time       time.Time
+	Target     string
+	Module     string
+	Duration   time.Duration
+	Collectors []collectorStatus
+	// Partial is set if only some of the collectors of the module were run,
+	// e.g. because of collect[] or exclude[] parameters.
+	Partial bool
+
+	// The most recent error of any collector, which may be from an earlier
+	// scrape.
+	LastError     string
+	LastErrorTime time.Time
+}
+
+// collectorStatus is the outcome of a single collector of a scrape.
+type collectorStatus struct {
+	Name     string
+	Duration time.Duration
+	Err      error
+}
+
+// Reason returns the reason the collector failed with, as exported in the
+// ipmi_collector_error metric.
+func (s collectorStatus) Reason() string {
+	if s.Err == nil {
+		return ""
+	}
+	return classifyError(s.Err)
+}
+
+// scrapeLog keeps the status of the latest scrape of recently scraped
+// targets, for display on the landing page.
+type scrapeLog struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]*scrapeStatus
+}
+
+func newScrapeLog(size int) *scrapeLog {
+	return &scrapeLog{
+		size:    size,
+		entries: map[string]*scrapeStatus{},
+	}
+}
+
+// record stores the status of a scrape, replacing that of the previous scrape
+// of the same target/module. A partial scrape only replaces the status of the
+// collectors it ran. If more targets than the size of the log were scraped,
+// the one scraped longest ago is dropped.
+func (l *scrapeLog) record(s scrapeStatus) {
+	if l.size <= 0 {
+		return
+	}
+	for _, c := range s.Collectors {
+		if c.Err != nil {
+			s.LastError = c.Name + ": " + c.Err.Error()
+			s.LastErrorTime = s.Time
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	key := targetKey(s.Target, s.Module)
+	if prev, ok := l.entries[key]; ok {
+		if s.LastError == "" {
+			s.LastError, s.LastErrorTime = prev.LastError, prev.LastErrorTime
+		}
+		if s.Partial {
+			s.Collectors = mergeCollectorStatus(prev.Collectors, s.Collectors)
+		}
+	}
+	l.entries[key] = &s
+	if len(l.entries) <= l.size {
+		return
+	}
+	var oldest string
+	for k, e := range l.entries {
+		if oldest == "" || e.Time.Before(l.entries[oldest].Time) {
+			oldest = k
+		}
+	}
+	delete(l.entries, oldest)
+}
+
+// mergeCollectorStatus returns the status of the collectors of a previous
+// scrape, updated with those of a partial scrape.
+func mergeCollectorStatus(prev, partial []collectorStatus) []collectorStatus {
+	result := slices.Clone(prev)
+	for _, c := range partial {
+		if i := slices.IndexFunc(result, func(p collectorStatus) bool { return p.Name == c.Name }); i >= 0 {
+			result[i] = c
+		} else {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// recent returns the status of all scrapes in the log, most recent first.
+func (l *scrapeLog) recent() []scrapeStatus {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	result := make([]scrapeStatus, 0, len(l.entries))
+	for _, e := range l.entries {
+		result = append(result, *e)
+	}
+	slices.SortFunc(result, func(a, b scrapeStatus) int {
+		return cmp.Compare(b.Time.UnixNano(), a.Time.UnixNano())
+	})
+	return result
+}
@@ -0,0 +1,67 @@
+// Copyright 2025 The Prometheus Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestScrapeLogMergesPartialScrapes(t *testing.T) {
+	l := newScrapeLog(10)
+	now := time.Now()
+	l.record(scrapeStatus{
+		Time:   now,
+		Target: "bmc",
+		Module: "default",
+		Collectors: []collectorStatus{
+			{Name: "ipmi"},
+			{Name: "bmc"},
+		},
+	})
+	l.record(scrapeStatus{
+		Time:       now.Add(time.Minute),
+		Target:     "bmc",
+		Module:     "default",
+		Collectors: []collectorStatus{{Name: "bmc", Err: errors.New("timeout")}, {Name: "sel"}},
+		Partial:    true,
+	})
+
+	recent := l.recent()
+	if len(recent) != 1 {
+		t.Fatalf("got %d entries, want 1", len(recent))
+	}
+	var names []string
+	for _, c := range recent[0].Collectors {
+		names = append(names, c.Name)
+	}
+	if want := []string{"ipmi", "bmc", "sel"}; !slices.Equal(names, want) {
+		t.Errorf("collectors: got %q, want %q", names, want)
+	}
+	if recent[0].Collectors[1].Err == nil {
+		t.Error("status of bmc collector not updated by partial scrape")
+	}
+
+	l.record(scrapeStatus{
+		Time:       now.Add(2 * time.Minute),
+		Target:     "bmc",
+		Module:     "default",
+		Collectors: []collectorStatus{{Name: "ipmi"}},
+	})
+	if got := len(l.recent()[0].Collectors); got != 1 {
+		t.Errorf("full scrape kept %d collectors, want 1", got)
+	}
+}